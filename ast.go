@@ -0,0 +1,438 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/* expression tree built by VerbalExpression methods, rendered to RE2 syntax at compile time */
+
+// node is an element of the expression tree. Each builder method appends
+// one node to the current sequence.
+type node interface {
+	isNode()
+}
+
+// literal matches text as is
+type literal struct {
+	text string
+}
+
+// classItem is a character range, lo == hi for a single character. If name
+// is set, the item is a predefined class such as `\w`.
+type classItem struct {
+	lo, hi rune
+	name   string
+}
+
+// class matches one character of (or not of, if negate is set) items
+type class struct {
+	negate bool
+	items  []classItem
+}
+
+// anyChar matches any character, "\n" only if DOTALL is set
+type anyChar struct{}
+
+// anchorKind is the RE2 syntax of an anchor
+type anchorKind string
+
+const (
+	startOfLine     anchorKind = "^"
+	endOfLine       anchorKind = "$"
+	startOfInput    anchorKind = `\A`
+	endOfInput      anchorKind = `\z`
+	wordBoundary    anchorKind = `\b`
+	notWordBoundary anchorKind = `\B`
+)
+
+// anchor matches a position, not a character
+type anchor struct {
+	kind anchorKind
+}
+
+// sequence matches nodes one after the other
+type sequence struct {
+	nodes []node
+}
+
+// alternation matches one of alts, tried in order
+type alternation struct {
+	alts []node
+}
+
+// group wraps body in parenthesis, capturing or not. A capturing group may
+// have a name. Flags in on and off are set and unset for the body only.
+type group struct {
+	capture bool
+	name    string
+	on, off Flag
+	body    node
+}
+
+// quantifier repeats child from min to max times, max is -1 when there is
+// no upper bound. A lazy quantifier matches as few times as possible, even
+// in ungreedy mode
+type quantifier struct {
+	child    node
+	min, max int
+	lazy     bool
+}
+
+// setFlags activates flags until the end of the enclosing group
+type setFlags struct {
+	flags Flag
+}
+
+// raw is a fragment of RE2 syntax written by hand. It is parsed when added
+// to know its capture groups, and if it can be repeated without a group.
+type raw struct {
+	text   string
+	caps   int
+	names  []string
+	atomic bool
+}
+
+func (*literal) isNode()     {}
+func (*class) isNode()       {}
+func (*anyChar) isNode()     {}
+func (*anchor) isNode()      {}
+func (*sequence) isNode()    {}
+func (*alternation) isNode() {}
+func (*group) isNode()       {}
+func (*quantifier) isNode()  {}
+func (*setFlags) isNode()    {}
+func (*raw) isNode()         {}
+
+// add appends n to the sequence, nested sequences are flattened
+func (s *sequence) add(n node) {
+	if seq, ok := n.(*sequence); ok {
+		s.nodes = append(s.nodes, seq.nodes...)
+		return
+	}
+	s.nodes = append(s.nodes, n)
+}
+
+// last returns the last node of the sequence, or nil if it is empty
+func (s *sequence) last() node {
+	if len(s.nodes) == 0 {
+		return nil
+	}
+	return s.nodes[len(s.nodes)-1]
+}
+
+// walk calls f for n and each of its descendants, depth first
+func walk(n node, f func(node)) {
+	f(n)
+	switch n := n.(type) {
+	case *sequence:
+		for _, child := range n.nodes {
+			walk(child, f)
+		}
+	case *alternation:
+		for _, alt := range n.alts {
+			walk(alt, f)
+		}
+	case *group:
+		walk(n.body, f)
+	case *quantifier:
+		walk(n.child, f)
+	}
+}
+
+// chars returns class items matching each rune of s
+func chars(s string) []classItem {
+	items := make([]classItem, 0, len(s))
+	for _, r := range s {
+		items = append(items, classItem{lo: r, hi: r})
+	}
+	return items
+}
+
+// copyNode returns a deep copy of n, so the copy is not modified when n's
+// expression is built further.
+func copyNode(n node) node {
+	switch n := n.(type) {
+	case *literal:
+		c := *n
+		return &c
+	case *class:
+		c := *n
+		c.items = append([]classItem(nil), n.items...)
+		return &c
+	case *anyChar:
+		return &anyChar{}
+	case *anchor:
+		c := *n
+		return &c
+	case *sequence:
+		c := &sequence{nodes: make([]node, len(n.nodes))}
+		for i, child := range n.nodes {
+			c.nodes[i] = copyNode(child)
+		}
+		return c
+	case *alternation:
+		c := &alternation{alts: make([]node, len(n.alts))}
+		for i, alt := range n.alts {
+			c.alts[i] = copyNode(alt)
+		}
+		return c
+	case *group:
+		c := *n
+		c.body = copyNode(n.body)
+		return &c
+	case *quantifier:
+		c := *n
+		c.child = copyNode(n.child)
+		return &c
+	case *setFlags:
+		c := *n
+		return &c
+	case *raw:
+		c := *n
+		c.names = append([]string(nil), n.names...)
+		return &c
+	}
+	panic("verbalexpressions: unknown node type")
+}
+
+// flagString returns RE2 flag letters for activated flags
+func flagString(f Flag) string {
+	flags := "misU" // warning, follow Flag const order
+	result := []rune{}
+
+	for i, flag := range flags {
+		if f&(1<<uint(i)) != 0 {
+			result = append(result, flag)
+		}
+	}
+
+	return string(result)
+}
+
+// regexFlags are flags rendered in the pattern, GLOBAL is not one of them
+const regexFlags = MULTILINE | IGNORE_CASE | DOTALL | UNGREEDY
+
+// position tells how a node is used by its parent, to know if it must be
+// wrapped in a non capturing group
+type position int
+
+const (
+	alone      position = iota // whole expression, group body or alternative
+	inSequence                 // with other nodes before or after
+	repeated                   // child of a quantifier
+)
+
+// renderer writes nodes in RE2 syntax
+type renderer struct {
+	strings.Builder
+	flags Flag // flags active at the current position
+}
+
+// render writes n, used at position pos
+func (r *renderer) render(n node, pos position) {
+	if r.needsGroup(n, pos) {
+		r.WriteString("(?:")
+		r.write(n, alone)
+		r.WriteString(")")
+		return
+	}
+	r.write(n, pos)
+}
+
+// write writes n, without the group needed for its position
+func (r *renderer) write(n node, pos position) {
+	switch n := n.(type) {
+	case *literal:
+		r.WriteString(quoteLiteral(n.text))
+	case *class:
+		r.renderClass(n)
+	case *anyChar:
+		r.WriteString(".")
+	case *anchor:
+		r.WriteString(string(n.kind))
+	case *sequence:
+		if len(n.nodes) == 1 {
+			// already grouped if needed, see needsGroup
+			r.write(n.nodes[0], pos)
+			return
+		}
+		for _, child := range n.nodes {
+			r.render(child, inSequence)
+		}
+	case *alternation:
+		for i, alt := range n.alts {
+			if i > 0 {
+				r.WriteString("|")
+			}
+			r.render(alt, alone)
+		}
+	case *group:
+		r.renderGroup(n, pos)
+	case *quantifier:
+		r.render(n.child, repeated)
+		r.WriteString(repeat(n.min, n.max))
+		// with U, "?" would make it greedy again
+		if n.lazy && r.flags&UNGREEDY == 0 {
+			r.WriteString("?")
+		}
+	case *setFlags:
+		if f := flagString(n.flags &^ r.flags); f != "" {
+			r.WriteString("(?" + f + ")")
+		}
+		r.flags |= n.flags & regexFlags
+	case *raw:
+		r.WriteString(n.text)
+	}
+}
+
+// needsGroup tells if n must be wrapped in a non capturing group to be
+// used at position pos
+func (r *renderer) needsGroup(n node, pos position) bool {
+	switch n := n.(type) {
+	case *literal:
+		return pos == repeated && utf8.RuneCountInString(n.text) != 1
+	case *class, *anyChar:
+		return false
+	case *raw:
+		// flags set in the fragment must not apply to next nodes
+		return !n.atomic
+	case *sequence:
+		if len(n.nodes) == 1 {
+			return r.needsGroup(n.nodes[0], pos)
+		}
+		return pos == repeated
+	case *alternation:
+		return pos != alone
+	case *group:
+		if !n.capture && r.flagsDelta(n) == "" {
+			// parenthesis are not written, it depends on the body
+			return r.needsGroup(n.body, pos)
+		}
+		return false
+	}
+	return pos == repeated
+}
+
+// flagsDelta returns the flags to set and unset for g, as RE2 "i-m" syntax,
+// only flags that are not already in the wanted state are returned
+func (r *renderer) flagsDelta(g *group) string {
+	delta := flagString(g.on &^ r.flags)
+	if off := flagString(g.off & r.flags); off != "" {
+		delta += "-" + off
+	}
+	return delta
+}
+
+// renderGroup writes a group, its flags apply only to its body
+func (r *renderer) renderGroup(g *group, pos position) {
+	delta := r.flagsDelta(g)
+	if !g.capture && delta == "" {
+		r.write(g.body, pos)
+		return
+	}
+
+	saved := r.flags
+	r.flags = (r.flags | g.on) &^ g.off
+	if delta != "" {
+		r.WriteString("(?" + delta + ":")
+	}
+	switch {
+	case g.name != "":
+		r.WriteString("(?P<" + g.name + ">")
+	case g.capture:
+		r.WriteString("(")
+	}
+	r.render(g.body, alone)
+	if g.capture {
+		r.WriteString(")")
+	}
+	if delta != "" {
+		r.WriteString(")")
+	}
+	r.flags = saved
+}
+
+// repeat returns the RE2 repetition operator
+func repeat(min, max int) string {
+	switch {
+	case min == 0 && max == 1:
+		return "?"
+	case min == 0 && max == -1:
+		return "*"
+	case min == 1 && max == -1:
+		return "+"
+	case max == -1:
+		return "{" + strconv.Itoa(min) + ",}"
+	case min == max:
+		return "{" + strconv.Itoa(min) + "}"
+	}
+	return "{" + strconv.Itoa(min) + "," + strconv.Itoa(max) + "}"
+}
+
+// renderClass writes a bracket expression, or the predefined class alone
+// if there is nothing else to match
+func (r *renderer) renderClass(c *class) {
+	b := &r.Builder
+	if !c.negate && len(c.items) == 1 && strings.HasPrefix(c.items[0].name, `\`) {
+		b.WriteString(c.items[0].name)
+		return
+	}
+	b.WriteString("[")
+	if c.negate {
+		b.WriteString("^")
+	}
+	for _, item := range c.items {
+		switch {
+		case item.name != "":
+			b.WriteString(item.name)
+		case item.lo == item.hi:
+			b.WriteString(quoteClassChar(item.lo))
+		default:
+			b.WriteString(quoteClassChar(item.lo) + "-" + quoteClassChar(item.hi))
+		}
+	}
+	b.WriteString("]")
+}
+
+// control characters are written escaped to keep the pattern readable
+var controlEscapes = map[rune]string{
+	'\t': `\t`,
+	'\n': `\n`,
+	'\v': `\v`,
+	'\f': `\f`,
+	'\r': `\r`,
+}
+
+// quoteLiteral escapes meta characters of s
+func quoteLiteral(s string) string {
+	s = quote(s)
+	if strings.IndexFunc(s, func(r rune) bool { return controlEscapes[r] != "" }) < 0 {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if e, ok := controlEscapes[r]; ok {
+			b.WriteString(e)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// quoteClassChar escapes r to be used in a bracket expression
+func quoteClassChar(r rune) string {
+	if e, ok := controlEscapes[r]; ok {
+		return e
+	}
+	switch r {
+	case '\\', '[', ']', '^', '-':
+		return `\` + string(r)
+	}
+	return string(r)
+}
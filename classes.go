@@ -0,0 +1,149 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+/* character classes */
+
+// CharClass is a set of characters matched as one character by Class().
+// Create one with NewClass(), then chain methods to add characters:
+//
+//	// match an identifier
+//	id := verbalexpressions.NewClass().Letter().Chars("_")
+//	v := verbalexpressions.New().Class(id).Class(id.Clone().Digit()).ZeroOrMore()
+type CharClass struct {
+	negate bool
+	items  []classItem
+	err    error
+}
+
+// NewClass returns an empty character class
+func NewClass() *CharClass {
+	return &CharClass{}
+}
+
+// predefined character sets
+var (
+	digitItems       = []classItem{{name: `\d`}}
+	whitespaceItems  = []classItem{{name: `\s`}}
+	letterItems      = []classItem{{lo: 'a', hi: 'z'}, {lo: 'A', hi: 'Z'}}
+	hexDigitItems    = []classItem{{lo: '0', hi: '9'}, {lo: 'A', hi: 'F'}, {lo: 'a', hi: 'f'}}
+	punctuationItems = []classItem{{name: `[:punct:]`}}
+	wordItems        = []classItem{{name: `\w`}}
+)
+
+// add appends items to the set
+func (c *CharClass) add(items []classItem) *CharClass {
+	c.items = append(c.items, items...)
+	return c
+}
+
+// Clone returns a copy of the class, to extend it without modifying c
+func (c *CharClass) Clone() *CharClass {
+	return &CharClass{
+		negate: c.negate,
+		items:  append([]classItem(nil), c.items...),
+		err:    c.err,
+	}
+}
+
+// Chars adds each character of string "s"
+func (c *CharClass) Chars(s string) *CharClass {
+	return c.add(chars(s))
+}
+
+// Range adds characters from "from" to "to", both included
+func (c *CharClass) Range(from, to rune) *CharClass {
+	if from > to {
+		if c.err == nil {
+			c.err = &StepError{Step: "Range", Args: []interface{}{from, to}, Err: ErrInvalidRange}
+		}
+		return c
+	}
+	return c.add([]classItem{{lo: from, hi: to}})
+}
+
+// Digit adds digits, 0 to 9
+func (c *CharClass) Digit() *CharClass {
+	return c.add(digitItems)
+}
+
+// Whitespace adds spaces, tabulations and line breaks
+func (c *CharClass) Whitespace() *CharClass {
+	return c.add(whitespaceItems)
+}
+
+// Letter adds ASCII letters, a to z in lower and upper case
+func (c *CharClass) Letter() *CharClass {
+	return c.add(letterItems)
+}
+
+// HexDigit adds hexadecimal digits, in lower and upper case
+func (c *CharClass) HexDigit() *CharClass {
+	return c.add(hexDigitItems)
+}
+
+// Punctuation adds ASCII punctuation characters such as "!", "." or "@"
+func (c *CharClass) Punctuation() *CharClass {
+	return c.add(punctuationItems)
+}
+
+// Union adds characters of other classes. Negated classes can't be part of
+// a union.
+func (c *CharClass) Union(others ...*CharClass) *CharClass {
+	for _, o := range others {
+		if o.err != nil && c.err == nil {
+			c.err = o.err
+		}
+		if o.negate && c.err == nil {
+			c.err = &StepError{Step: "Union", Err: ErrNegatedUnion}
+		}
+		c.add(o.items)
+	}
+	return c
+}
+
+// Not negates the class: it matches any character that is not in the set
+func (c *CharClass) Not() *CharClass {
+	c.negate = !c.negate
+	return c
+}
+
+// Class matches one character of the class c
+//
+//	// match "0x1F", "0xff"...
+//	v.Find("0x").Class(verbalexpressions.NewClass().HexDigit()).OneOrMore()
+func (v *VerbalExpression) Class(c *CharClass) *VerbalExpression {
+	if c.err != nil {
+		return v.fail("Class", c.err)
+	}
+	if len(c.items) == 0 {
+		return v.fail("Class", ErrEmptyClass)
+	}
+	return v.add(&class{negate: c.negate, items: append([]classItem(nil), c.items...)})
+}
+
+// Digit matches one digit, 0 to 9
+func (v *VerbalExpression) Digit() *VerbalExpression {
+	return v.add(&class{items: digitItems})
+}
+
+// Whitespace matches one space, tabulation or line break
+func (v *VerbalExpression) Whitespace() *VerbalExpression {
+	return v.add(&class{items: whitespaceItems})
+}
+
+// Letter matches one ASCII letter, in lower or upper case
+func (v *VerbalExpression) Letter() *VerbalExpression {
+	return v.add(&class{items: letterItems})
+}
+
+// HexDigit matches one hexadecimal digit, in lower or upper case
+func (v *VerbalExpression) HexDigit() *VerbalExpression {
+	return v.add(&class{items: hexDigitItems})
+}
+
+// Punctuation matches one ASCII punctuation character
+func (v *VerbalExpression) Punctuation() *VerbalExpression {
+	return v.add(&class{items: punctuationItems})
+}
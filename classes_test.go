@@ -0,0 +1,69 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPredefinedClasses(t *testing.T) {
+
+	tests := []struct {
+		v       *VerbalExpression
+		expect  string
+		match   string
+		nomatch string
+	}{
+		{New().Digit(), `(?m)\d`, "a1", "ab"},
+		{New().Whitespace(), `(?m)\s`, "a\tb", "ab"},
+		{New().Letter(), `(?m)[a-zA-Z]`, "1B", "12"},
+		{New().HexDigit().Times(2), `(?m)[0-9A-Fa-f]{2}`, "0xfF", "0xG"},
+		{New().Punctuation(), `(?m)[[:punct:]]`, "a!", "a b"},
+	}
+	for _, test := range tests {
+		assertStringEquals(test.v.String(), test.expect, t)
+		if !test.v.Test(test.match) || test.v.Test(test.nomatch) {
+			t.Errorf("%v should match %q and not %q", test.v.Regex(), test.match, test.nomatch)
+		}
+	}
+}
+
+func TestCharClass(t *testing.T) {
+
+	id := NewClass().Letter().Chars("_")
+	v := New().StartOfLine().Class(id).Class(id.Clone().Digit()).ZeroOrMore().EndOfLine()
+	assertStringEquals(v.String(), `(?m)^[a-zA-Z_][a-zA-Z_\d]*$`, t)
+	if !v.Test("_foo42") || v.Test("42foo") {
+		t.Errorf("%v should match _foo42 and not 42foo", v.Regex())
+	}
+
+	c := NewClass().Range('a', 'f').Union(NewClass().Chars("-]"), NewClass().Whitespace()).Not()
+	v = New().Class(c)
+	assertStringEquals(v.String(), `(?m)[^a-f\-\]\s]`, t)
+	if !v.Test("g") || v.Test("a- ]") {
+		t.Errorf("%v should match g and not a- ]", v.Regex())
+	}
+
+	// class is copied, modifying it doesn't modify the expression
+	c.Chars("z")
+	assertStringEquals(v.String(), `(?m)[^a-f\-\]\s]`, t)
+}
+
+func TestCharClassErrors(t *testing.T) {
+
+	if err := New().Class(NewClass()).Err(); !errors.Is(err, ErrEmptyClass) {
+		t.Errorf("%v is not ErrEmptyClass", err)
+	}
+	if err := New().Class(NewClass().Range('z', 'a')).Err(); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("%v is not ErrInvalidRange", err)
+	}
+	if err := New().Range("z", "a").Err(); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("%v is not ErrInvalidRange", err)
+	}
+	c := NewClass().Digit().Union(NewClass().Letter().Not())
+	if err := New().Class(c).Err(); !errors.Is(err, ErrNegatedUnion) {
+		t.Errorf("%v is not ErrNegatedUnion", err)
+	}
+}
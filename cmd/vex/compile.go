@@ -0,0 +1,125 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// runCompile prints the expression in the format of -format
+func runCompile(c *cli, args []string) error {
+	fs := c.flags("compile", "[-format f] [-e text | -f file | -r regex | file]")
+	var s source
+	s.add(fs)
+	format := fs.String("format", "regex", "output format: regex, go, json or dsl")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := s.fromArgs(fs.Args()); err != nil {
+		return err
+	}
+	switch *format {
+	case "regex", "go", "json", "dsl":
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	v, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "regex":
+		fmt.Fprintln(c.stdout, v.String())
+	case "go":
+		fmt.Fprintln(c.stdout, v.GoSource())
+	case "json":
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.stdout, string(data))
+	case "dsl":
+		fmt.Fprint(c.stdout, v.DSL())
+	}
+	return nil
+}
+
+// runTest prints the matches and captures of each string, the status is 1
+// if one of them doesn't match
+func runTest(c *cli, args []string) error {
+	fs := c.flags("test", "(-e text | -f file | -r regex) [string...]")
+	var s source
+	s.add(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	v, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		scanner := bufio.NewScanner(c.stdin)
+		for scanner.Scan() {
+			inputs = append(inputs, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	names := v.Regex().SubexpNames()
+	for _, input := range inputs {
+		fmt.Fprintln(c.stdout, input)
+		matches := v.Captures(input)
+		if len(matches) == 0 {
+			fmt.Fprintln(c.stdout, "  no match")
+			c.status = 1
+			continue
+		}
+		printMatches(c.stdout, "  ", names, matches)
+	}
+	return nil
+}
+
+// printMatches prints matches, as returned by Captures(), with their
+// capture groups of names. Lines start with indent.
+func printMatches(w io.Writer, indent string, names []string, matches [][]string) {
+	for i, m := range matches {
+		fmt.Fprintf(w, "%smatch %d: %q\n", indent, i+1, m[0])
+		for g := 1; g < len(m); g++ {
+			if names[g] != "" {
+				fmt.Fprintf(w, "%s  %d %s: %q\n", indent, g, names[g], m[g])
+			} else {
+				fmt.Fprintf(w, "%s  %d: %q\n", indent, g, m[g])
+			}
+		}
+	}
+}
+
+// runExplain prints the description of the expression
+func runExplain(c *cli, args []string) error {
+	fs := c.flags("explain", "[-e text | -f file | -r regex | file]")
+	var s source
+	s.add(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := s.fromArgs(fs.Args()); err != nil {
+		return err
+	}
+	v, err := s.load(c)
+	if err != nil {
+		return err
+	}
+	if explanation := v.Explain(); explanation != "" {
+		fmt.Fprintln(c.stdout, explanation)
+	}
+	return nil
+}
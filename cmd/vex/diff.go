@@ -0,0 +1,189 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines around changes of a diff
+const context = 3
+
+// edit is a line of a diff: kept, deleted or inserted
+type edit struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns the unified diff of a and b, the old and new content
+// of file name, or "" if they are the same
+func unifiedDiff(name, a, b string) string {
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	// line numbers before each edit, from 0
+	aLine, bLine := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	changes := []int{}
+	for i, e := range edits {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if e.kind != '+' {
+			aLine[i+1]++
+		}
+		if e.kind != '-' {
+			bLine[i+1]++
+		}
+		if e.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	for len(changes) > 0 {
+		// a hunk has the changes with at most 2*context lines between them
+		last := 0
+		for last+1 < len(changes) && changes[last+1]-changes[last]-1 <= 2*context {
+			last++
+		}
+		start, end := changes[0]-context, changes[last]+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(edits) {
+			end = len(edits)
+		}
+		changes = changes[last+1:]
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aLine[start], aLine[end]), hunkRange(bLine[start], bLine[end]))
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.kind)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+// hunkRange returns the lines from start to end, from 0, of a hunk header
+func hunkRange(start, end int) string {
+	switch end - start {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, end-start)
+}
+
+// splitLines returns the lines of s, with their line break
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edits changing a into b, with the linear
+// space algorithm of Eugene W. Myers, "An O(ND) Difference Algorithm and
+// Its Variations": the middle snake of the edits is found from both ends,
+// then the parts before and after it are diffed the same way
+func diffLines(a, b []string) []edit {
+	edits := []edit{}
+	diffRange(a, b, &edits)
+	return edits
+}
+
+// diffRange appends the edits changing a into b to edits
+func diffRange(a, b []string, edits *[]edit) {
+	// common prefix and suffix
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		p++
+	}
+	s := 0
+	for s < len(a)-p && s < len(b)-p && a[len(a)-1-s] == b[len(b)-1-s] {
+		s++
+	}
+	for _, line := range a[:p] {
+		*edits = append(*edits, edit{' ', line})
+	}
+	suffix := a[len(a)-s:]
+	a, b = a[p:len(a)-s], b[p:len(b)-s]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			*edits = append(*edits, edit{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			*edits = append(*edits, edit{'-', line})
+		}
+	default:
+		// without common prefix and suffix, there are 2 edits at least,
+		// each part is smaller than a and b
+		x, y, u, v := middleSnake(a, b)
+		diffRange(a[:x], b[:y], edits)
+		for _, line := range a[x:u] {
+			*edits = append(*edits, edit{' ', line})
+		}
+		diffRange(a[u:], b[v:], edits)
+	}
+
+	for _, line := range suffix {
+		*edits = append(*edits, edit{' ', line})
+	}
+}
+
+// middleSnake returns the start x, y and the end u, v of the equal lines in
+// the middle of the shortest edits changing a into b
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	offset := max + 1
+	// furthest x on each diagonal k, from the start and from the end
+	forward, backward := make([]int, 2*offset+1), make([]int, 2*offset+1)
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			x := forward[offset+k-1] + 1
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+			// diagonal k is delta-k from the end, reached with d-1 edits
+			if r := delta - k; odd && r >= -(d-1) && r <= d-1 && x+backward[offset+r] >= n {
+				return sx, sy, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			x := backward[offset+k-1] + 1
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				x = backward[offset+k+1]
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x, y = x+1, y+1
+			}
+			backward[offset+k] = x
+			if r := delta - k; !odd && r >= -d && r <= d && x+forward[offset+r] >= n {
+				return n - x, m - y, n - sx, m - sy
+			}
+		}
+	}
+	panic("no middle snake")
+}
@@ -0,0 +1,116 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+
+	tests := []struct {
+		a, b, expect string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n", "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			"--- f\n+++ f\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{"a\n", "", "--- f\n+++ f\n@@ -1 +0,0 @@\n-a\n"},
+		{"", "a\nb\n", "--- f\n+++ f\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"a\nb", "a\nc", "--- f\n+++ f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"},
+		{
+			"x\n1\n2\n3\n4\n5\n6\n7\n8\ny\n", "X\n1\n2\n3\n4\n5\n6\n7\n8\nY\n",
+			"--- f\n+++ f\n@@ -1,4 +1,4 @@\n-x\n+X\n 1\n 2\n 3\n@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-y\n+Y\n",
+		},
+		{
+			"x\n1\n2\n3\n4\n5\n6\ny\n", "X\n1\n2\n3\n4\n5\n6\nY\n",
+			"--- f\n+++ f\n@@ -1,8 +1,8 @@\n-x\n+X\n 1\n 2\n 3\n 4\n 5\n 6\n-y\n+Y\n",
+		},
+	}
+	for _, test := range tests {
+		if diff := unifiedDiff("f", test.a, test.b); diff != test.expect {
+			t.Errorf("%q %q: diff is\n%s\nnot\n%s", test.a, test.b, diff, test.expect)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(20))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		var old, new []string
+		changes := 0
+		for _, e := range diffLines(a, b) {
+			if e.kind != '+' {
+				old = append(old, e.line)
+			}
+			if e.kind != '-' {
+				new = append(new, e.line)
+			}
+			if e.kind != ' ' {
+				changes++
+			}
+		}
+		if strings.Join(old, "") != strings.Join(a, "") || strings.Join(new, "") != strings.Join(b, "") {
+			t.Fatalf("edits of %q and %q make %q and %q", a, b, old, new)
+		}
+		if shortest := len(a) + len(b) - 2*lcs(a, b); changes != shortest {
+			t.Fatalf("edits of %q and %q have %d changes, not %d", a, b, changes, shortest)
+		}
+	}
+}
+
+// lcs returns the length of the longest common subsequence of a and b
+func lcs(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLargeFile(t *testing.T) {
+
+	// a whole rewrite is the worst case: memory must not grow with the
+	// square of the lines
+	var a, b strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&a, "v1.%d\n", i)
+		fmt.Fprintf(&b, "v2.%d\n", i)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	diff := unifiedDiff("f", a.String(), b.String())
+	runtime.ReadMemStats(&after)
+
+	if !strings.HasPrefix(diff, "--- f\n+++ f\n@@ -1,5000 +1,5000 @@\n-v1.0\n") || strings.Count(diff, "\n") != 10003 {
+		t.Errorf("diff starts with\n%.100s", diff)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+		t.Errorf("%d MB allocated", alloc>>20)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// runGrep prints the lines of files matching the expression. The status is
+// 1 if no line matches, 2 if a file can't be read.
+func runGrep(c *cli, args []string) error {
+	fs := c.flags("grep", "(-e text | -f file | -r regex) [-o] [-c] [-g groups] [-j n] [path...]")
+	var s source
+	s.add(fs)
+	only := fs.Bool("o", false, "print each match instead of the line")
+	count := fs.Bool("c", false, "print the number of matching lines of each file, or of matches with -o")
+	groups := fs.String("g", "", "print the capture groups of each match in tab separated columns, numbers or names separated by commas")
+	workers := fs.Int("j", runtime.NumCPU(), "number of files searched at the same time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *workers < 1 {
+		return fmt.Errorf("invalid -j %d", *workers)
+	}
+	v, err := s.load(c)
+	if err != nil {
+		return err
+	}
+	g := &grep{v: v, only: *only, count: *count}
+	if g.columns, err = columns(v, *groups); err != nil {
+		return err
+	}
+
+	c.status = 1
+	report := func(r result) {
+		c.stdout.Write(r.out)
+		if r.err != nil {
+			fmt.Fprintf(c.stderr, "vex grep: %v\n", r.err)
+			c.status = 2
+		} else if r.count > 0 && c.status == 1 {
+			c.status = 0
+		}
+	}
+
+	if fs.NArg() == 0 {
+		report(g.search(c.stdin, "<stdin>"))
+		return nil
+	}
+	for r := range walk(fs.Args(), *workers, g.file) {
+		report(r)
+	}
+	return nil
+}
+
+// grep searches files for lines matching v
+type grep struct {
+	v       *verbalexpressions.VerbalExpression
+	only    bool  // print matches instead of lines
+	count   bool  // print counts instead of lines
+	columns []int // capture groups to print, nil to print lines
+}
+
+// columns returns the indexes of the capture groups of list, numbers or
+// names separated by commas
+func columns(v *verbalexpressions.VerbalExpression, list string) ([]int, error) {
+	if list == "" {
+		return nil, nil
+	}
+	names := v.Regex().SubexpNames()
+	cols := []int{}
+	for _, group := range strings.Split(list, ",") {
+		group = strings.TrimSpace(group)
+		i, err := strconv.Atoi(group)
+		if err != nil {
+			i = -1
+			for n, name := range names {
+				if name == group && name != "" {
+					i = n
+				}
+			}
+		}
+		if i < 0 || i >= len(names) {
+			return nil, fmt.Errorf("unknown capture group %q", group)
+		}
+		cols = append(cols, i)
+	}
+	return cols, nil
+}
+
+// file searches the file path, binary files are skipped
+func (g *grep) file(path string) result {
+	f, err := os.Open(path)
+	if err != nil {
+		return result{err: err}
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if head, _ := r.Peek(512); binary(head) {
+		return result{}
+	}
+	return g.search(r, path)
+}
+
+// search searches the lines of r, named name in the output
+func (g *grep) search(r io.Reader, name string) result {
+	var out bytes.Buffer
+	matching, matches := 0, 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		// as Captures(), all the matches unless StopAtFirst(true) is set
+		found := g.v.CapturesIndex(line)
+		if len(found) == 0 {
+			continue
+		}
+		matching++
+		matches += len(found)
+
+		switch {
+		case g.count:
+		case g.columns != nil:
+			for _, m := range found {
+				values := make([]string, len(g.columns))
+				for i, col := range g.columns {
+					if m[2*col] >= 0 {
+						values[i] = line[m[2*col]:m[2*col+1]]
+					}
+				}
+				fmt.Fprintf(&out, "%s:%d:%d:%s\n", name, n, m[0]+1, strings.Join(values, "\t"))
+			}
+		case g.only:
+			for _, m := range found {
+				fmt.Fprintf(&out, "%s:%d:%d:%s\n", name, n, m[0]+1, line[m[0]:m[1]])
+			}
+		default:
+			fmt.Fprintf(&out, "%s:%d:%d:%s\n", name, n, found[0][0]+1, line)
+		}
+	}
+	if g.count {
+		if g.only {
+			matching = matches
+		}
+		fmt.Fprintf(&out, "%s:%d\n", name, matching)
+	}
+	err := scanner.Err()
+	if err != nil {
+		err = fmt.Errorf("%s: %v", name, err)
+	}
+	return result{out: out.Bytes(), count: matches, err: err}
+}
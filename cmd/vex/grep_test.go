@@ -0,0 +1,122 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tree writes files in a temporary directory and returns it
+func tree(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGrep(t *testing.T) {
+
+	dir := tree(t, map[string]string{
+		"a.txt":       "id 12 and 34\nnothing\n56\n",
+		"sub/b.txt":   "x7\n",
+		"sub/c.bin":   "8\x00\n",
+		"sub/d/e.txt": "none\n",
+	})
+	expr := `capture as n: digit / one or more`
+
+	tests := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"-e", expr}, "a.txt:1:4:id 12 and 34\na.txt:3:1:56\nsub/b.txt:1:2:x7\n"},
+		{[]string{"-e", expr, "-o"}, "a.txt:1:4:12\na.txt:1:11:34\na.txt:3:1:56\nsub/b.txt:1:2:7\n"},
+		{[]string{"-e", "stop at first / " + expr, "-o"}, "a.txt:1:4:12\na.txt:3:1:56\nsub/b.txt:1:2:7\n"},
+		{[]string{"-e", expr, "-c"}, "a.txt:2\nsub/b.txt:1\nsub/d/e.txt:0\n"},
+		{[]string{"-e", expr, "-c", "-o"}, "a.txt:3\nsub/b.txt:1\nsub/d/e.txt:0\n"},
+		{[]string{"-r", `(\w)(?P<n>\d)`, "-g", "n,1"}, "a.txt:1:4:2\t1\na.txt:1:11:4\t3\na.txt:3:1:6\t5\nsub/b.txt:1:1:7\tx\n"},
+	}
+	for _, test := range tests {
+		for _, workers := range []string{"1", "3"} {
+			args := append([]string{"grep", "-j", workers}, test.args...)
+			out, errs, status := vex("", append(args, dir)...)
+			out = strings.ReplaceAll(filepath.ToSlash(out), filepath.ToSlash(dir)+"/", "")
+			if out != test.expect || status != 0 {
+				t.Errorf("%v: output is\n%s\nnot\n%s, status %d %s", args, out, test.expect, status, errs)
+			}
+		}
+	}
+}
+
+func TestGrepLinks(t *testing.T) {
+
+	dir := tree(t, map[string]string{"a.txt": "v1\n", "sub/b.txt": "v2\n"})
+	links := map[string]string{"link.txt": "a.txt", "sub/link.txt": "../a.txt", "dir": "sub", "broken": "none"}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skip(err)
+		}
+	}
+	path := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	// links given as arguments are followed, not links in directories
+	tests := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{path("link.txt")}, "link.txt:1:1:v1\n"},
+		{[]string{path("dir")}, "dir/b.txt:1:1:v2\n"},
+		{[]string{path("sub")}, "sub/b.txt:1:1:v2\n"},
+	}
+	for _, test := range tests {
+		out, errs, status := vex("", append([]string{"grep", "-e", `then "v"`}, test.args...)...)
+		out = strings.ReplaceAll(filepath.ToSlash(out), filepath.ToSlash(dir)+"/", "")
+		if out != test.expect || errs != "" || status != 0 {
+			t.Errorf("%v: output %q, errors %q, status %d", test.args, out, errs, status)
+		}
+	}
+
+	_, errs, status := vex("", "grep", "-e", `then "v"`, path("broken"), path("a.txt"))
+	if status != 2 || !strings.HasPrefix(errs, "vex grep: stat "+path("broken")) {
+		t.Errorf("errors %q, status %d", errs, status)
+	}
+}
+
+func TestGrepStdin(t *testing.T) {
+
+	out, _, status := vex("a\nb1\n", "grep", "-e", "digit")
+	if out != "<stdin>:2:2:b1\n" || status != 0 {
+		t.Errorf("output is %q, status %d", out, status)
+	}
+	out, _, status = vex("a\n", "grep", "-e", "digit")
+	if out != "" || status != 1 {
+		t.Errorf("output is %q, status %d", out, status)
+	}
+}
+
+func TestGrepErrors(t *testing.T) {
+
+	dir := tree(t, map[string]string{"a.txt": "1\n"})
+	missing := filepath.Join(dir, "missing")
+	out, errs, status := vex("", "grep", "-e", "digit", filepath.Join(dir, "a.txt"), missing)
+	if out != filepath.Join(dir, "a.txt")+":1:1:1\n" || status != 2 || !strings.Contains(errs, missing) {
+		t.Errorf("output is %q, errors %q, status %d", out, errs, status)
+	}
+
+	_, errs, status = vex("", "grep", "-e", "digit", "-g", "host", dir)
+	if status != 2 || errs != "vex grep: unknown capture group \"host\"\n" {
+		t.Errorf("errors %q, status %d", errs, status)
+	}
+}
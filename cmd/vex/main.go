@@ -0,0 +1,283 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+
+// Vex builds, tests and explains verbal expressions, and searches and
+// rewrites files with them, from the command line, without writing a Go
+// program.
+//
+// Usage:
+//
+//	vex <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	compile  print the regular expression, Go code, JSON or DSL of an expression
+//	test     match strings against an expression and print captures
+//	explain  describe an expression in english
+//	grep     print lines of files matching an expression
+//	replace  replace matches of an expression in files
+//	repl     build an expression step by step, matching a sample corpus
+//	serve    serve a web playground matching expressions against sample text
+//
+// Expressions are given with one of the flags:
+//
+//	-e text   the expression in the language of ParseDSL
+//	-f file   a file with the expression, in the DSL or in JSON, - for the
+//	          standard input
+//	-r regex  a regular expression, converted with FromRegex
+//
+// For example:
+//
+//	$ vex compile -e 'start of line / then "http" / maybe "s"'
+//	(?m)^https?
+//	$ vex test -r '(?P<year>\d{4})-\d{2}' 2013-07 hello
+//	2013-07
+//	  match 1: "2013-07"
+//	    1 year: "2013"
+//	hello
+//	  no match
+//
+// Compile and explain read the expression from the file argument, or from
+// the standard input, if no flag gives it. Test matches its arguments, or
+// the lines of the standard input if there are none, and exits with status
+// 1 if one of them doesn't match.
+//
+// Grep searches files, and the files of directories, or the standard input
+// if there are none. It prints matching lines as file:line:column:text,
+// the column is the byte offset of the first match, from 1. Lines match as
+// with Captures(): with the -o flag, each match is printed on its own line,
+// all of them unless the expression has StopAtFirst(true). The -g flag
+// prints capture groups instead, -c the number of matching lines of each
+// file. Files are searched concurrently, -j at the same time, but results
+// are printed in order. Binary files, with a NUL byte in their first 512
+// bytes, are skipped, as links found in directories: links given as
+// arguments are followed. As grep, vex grep exits with status 1 if no line
+// matches, 2 if a file can't be read.
+//
+// Replace replaces the matches in files, or in the standard input, with
+// the template of -with, as Replace() does: $1 or ${name} is the text of a
+// capture group. It prints the new content of the files, or their unified
+// diff with -dry-run. With -in-place, it rewrites the files instead: the
+// new content is written to a temporary file, renamed to the file, or to
+// the target of a link given as argument, and -backup keeps the original
+// content in a file with a suffix, as -backup .orig. The number of
+// replacements of each file is printed on the standard error. The status
+// is 1 if nothing is replaced.
+//
+// Repl reads steps in the DSL, a line at a time, and prints the regular
+// expression, the explanation and the matches, with their capture groups,
+// in the lines of the -corpus file after each of them:
+//
+//	$ vex repl -corpus urls.txt
+//	> then "http" / maybe "s"
+//	> capture word
+//	> undo
+//
+// "undo" removes the steps of the last line, "reset" all of them, "load"
+// reads another corpus and "help" lists the commands.
+//
+// Serve serves a web page on -addr, localhost:8080 by default, to write an
+// expression, in the DSL, in JSON or as a regular expression, and see its
+// matches and capture groups highlighted in a sample text. The page needs
+// no network access, its files are in the vex binary. It only listens on
+// the loopback interface. The page uses a JSON API: a POST of
+//
+//	{"expression": "then \"a\" / capture: digit", "text": "a1 a2"}
+//
+// to /api/match, or with "regex" instead of "expression", is answered with
+// the regular expression, the explanation, the DSL, the names of the
+// capture groups and the matches, as Captures(): the match then its
+// capture groups, with their byte offsets in the text, -1 for groups that
+// didn't match. Invalid expressions are answered with status 400 and
+// {"error": message}, with the line and the column of DSL errors.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// command is a subcommand of vex
+type command struct {
+	name  string
+	short string
+	run   func(c *cli, args []string) error
+}
+
+// commands are the subcommands, in the order of the usage
+var commands = []command{
+	{"compile", "print the regular expression, Go code, JSON or DSL of an expression", runCompile},
+	{"test", "match strings against an expression and print captures", runTest},
+	{"explain", "describe an expression in english", runExplain},
+	{"grep", "print lines of files matching an expression", runGrep},
+	{"replace", "replace matches of an expression in files", runReplace},
+	{"repl", "build an expression step by step, matching a sample corpus", runRepl},
+	{"serve", "serve a web playground matching expressions against sample text", runServe},
+}
+
+// cli is the environment of a command
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	status         int // exit status of a command returning no error
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command of args and returns the exit status: 2 for errors
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		c.usage()
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(c, args[1:])
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case err != nil:
+			fmt.Fprintf(stderr, "vex %s: %v\n", cmd.name, err)
+			return 2
+		}
+		return c.status
+	}
+	fmt.Fprintf(stderr, "vex: unknown command %q\n", args[0])
+	c.usage()
+	return 2
+}
+
+// usage prints the commands
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "usage: vex <command> [flags] [arguments]\n\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-8s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintln(c.stderr, "\nrun vex <command> -h for the flags of a command")
+}
+
+// flags returns the flag set of command name taking args, errors are
+// returned to run
+func (c *cli) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet("vex "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: vex %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// source are the flags giving the expression of a command
+type source struct {
+	text, file, regex string
+}
+
+// add adds the flags of s to fs
+func (s *source) add(fs *flag.FlagSet) {
+	fs.StringVar(&s.text, "e", "", "expression in the DSL")
+	fs.StringVar(&s.file, "f", "", "file of the expression, in the DSL or in JSON, - for the standard input")
+	fs.StringVar(&s.regex, "r", "", "regular expression")
+}
+
+// given tells if a flag gives the expression
+func (s *source) given() bool {
+	return s.text != "" || s.file != "" || s.regex != ""
+}
+
+// fromArgs reads the expression from the file argument, or from the
+// standard input, if no flag gives it
+func (s *source) fromArgs(args []string) error {
+	switch {
+	case s.given() && len(args) > 0:
+		return errors.New("unexpected arguments: " + strings.Join(args, " "))
+	case s.given():
+	case len(args) == 0:
+		s.file = "-"
+	case len(args) == 1:
+		s.file = args[0]
+	default:
+		return errors.New("only one file is allowed")
+	}
+	return nil
+}
+
+// load returns the compiled expression given by the flags
+func (s *source) load(c *cli) (*verbalexpressions.VerbalExpression, error) {
+	n := 0
+	for _, f := range []string{s.text, s.file, s.regex} {
+		if f != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errors.New("give the expression with one of -e, -f or -r")
+	}
+
+	var v *verbalexpressions.VerbalExpression
+	var err error
+	switch {
+	case s.regex != "":
+		v, err = verbalexpressions.FromRegex(s.regex)
+	case s.text != "":
+		v, err = parse(s.text, "-e")
+	default:
+		var data []byte
+		name := s.file
+		if name == "-" {
+			name = "<stdin>"
+			data, err = io.ReadAll(c.stdin)
+		} else {
+			data, err = os.ReadFile(s.file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		v, err = parse(string(data), name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := v.Compile(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// parse returns the expression of src, in JSON if it is an object, in the
+// DSL otherwise. Errors start with name, and the line and column in the DSL.
+func parse(src, name string) (*verbalexpressions.VerbalExpression, error) {
+	if strings.HasPrefix(strings.TrimSpace(src), "{") {
+		v := verbalexpressions.New()
+		if err := v.UnmarshalJSON([]byte(src)); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return v, nil
+	}
+
+	v, err := verbalexpressions.ParseDSL(src)
+	var de *verbalexpressions.DSLError
+	if errors.As(err, &de) {
+		if de.Text != "" {
+			return nil, fmt.Errorf("%s:%d:%d: %s: %v", name, de.Line, de.Column, de.Text, de.Err)
+		}
+		return nil, fmt.Errorf("%s:%d:%d: %v", name, de.Line, de.Column, de.Err)
+	}
+	return v, err
+}
@@ -0,0 +1,119 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// vex runs vex with args and stdin, and returns its outputs and status
+func vex(stdin string, args ...string) (stdout, stderr string, status int) {
+	var out, errs bytes.Buffer
+	status = run(args, strings.NewReader(stdin), &out, &errs)
+	return out.String(), errs.String(), status
+}
+
+func TestCompile(t *testing.T) {
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "url.vex")
+	if err := os.WriteFile(file, []byte("start of line\nthen \"http\"\nmaybe \"s\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jsonFile := filepath.Join(dir, "url.json")
+	json := `{"steps":[{"step":"Find","args":["http"]},{"step":"Maybe","args":["s"]}]}`
+	if err := os.WriteFile(jsonFile, []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		stdin  string
+		args   []string
+		expect string
+	}{
+		{"", []string{"compile", "-e", `start of line / then "http" / maybe "s"`}, "(?m)^https?\n"},
+		{"", []string{"compile", file}, "(?m)^https?\n"},
+		{"", []string{"compile", "-f", jsonFile}, "(?m)https?\n"},
+		{"then \"a\"\ndigit\n", []string{"compile"}, "(?m)a\\d\n"},
+		{json, []string{"compile", "-f", "-"}, "(?m)https?\n"},
+		{"", []string{"compile", "-format", "go", "-r", `^a+$`}, "verbalexpressions.New().\n\tSearchOneLine(true).\n\tStartOfLine().\n\tMultiple(\"a\").\n\tEndOfLine()\n"},
+		{"", []string{"compile", "-format", "dsl", "-r", `\d{2}`}, "digit\ntimes 2\n"},
+		{"", []string{"compile", "-format", "json", "-e", "digit"}, "{\n  \"flags\": [\n    \"multiline\",\n    \"global\"\n  ],\n  \"steps\": [\n    {\n      \"step\": \"Digit\"\n    }\n  ]\n}\n"},
+	}
+	for _, test := range tests {
+		out, errs, status := vex(test.stdin, test.args...)
+		if status != 0 {
+			t.Errorf("%v: status %d, %s", test.args, status, errs)
+		}
+		if out != test.expect {
+			t.Errorf("%v: output is\n%q\nnot\n%q", test.args, out, test.expect)
+		}
+	}
+}
+
+func TestTest(t *testing.T) {
+
+	out, _, status := vex("", "test", "-r", `(?P<year>\d{4})-(\d{2})`, "2013-07 and 2014-01", "hello")
+	expect := `2013-07 and 2014-01
+  match 1: "2013-07"
+    1 year: "2013"
+    2: "07"
+  match 2: "2014-01"
+    1 year: "2014"
+    2: "01"
+hello
+  no match
+`
+	if out != expect {
+		t.Errorf("output is\n%s\nnot\n%s", out, expect)
+	}
+	if status != 1 {
+		t.Errorf("status is %d, not 1", status)
+	}
+
+	out, _, status = vex("a1\nb2\n", "test", "-e", "letter / digit")
+	expect = "a1\n  match 1: \"a1\"\nb2\n  match 1: \"b2\"\n"
+	if out != expect || status != 0 {
+		t.Errorf("output is\n%s\nnot\n%s, status %d", out, expect, status)
+	}
+}
+
+func TestExplain(t *testing.T) {
+
+	out, _, status := vex("", "explain", "-e", `then "a" / capture: digit`)
+	expect := "options: multiline\nthe text \"a\"\ncapture group 1:\n  a digit\n"
+	if out != expect || status != 0 {
+		t.Errorf("output is\n%s\nnot\n%s, status %d", out, expect, status)
+	}
+}
+
+func TestErrors(t *testing.T) {
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"frobnicate"}, `vex: unknown command "frobnicate"`},
+		{[]string{"compile", "-e", "then \"a\"\nfrobnicate"}, "vex compile: -e:2:1: frobnicate: unknown step"},
+		{[]string{"compile", "-r", "a("}, "vex compile: error parsing regexp: missing closing ): `a(`"},
+		{[]string{"compile", "-format", "xml", "-e", "digit"}, `vex compile: unknown format "xml"`},
+		{[]string{"compile", "-e", "digit", "file"}, "vex compile: unexpected arguments: file"},
+		{[]string{"compile", "-e", "digit", "-r", "a"}, "vex compile: give the expression with one of -e, -f or -r"},
+		{[]string{"test", "a"}, "vex test: give the expression with one of -e, -f or -r"},
+		{[]string{"explain", "-e", `{"steps":[{"step":"Nope"}]}`}, "vex explain: -e: verbalexpressions: Nope(): unknown step"},
+	}
+	for _, test := range tests {
+		_, errs, status := vex("", test.args...)
+		if status != 2 {
+			t.Errorf("%v: status is %d, not 2", test.args, status)
+		}
+		if first := strings.SplitN(errs, "\n", 2)[0]; first != test.err {
+			t.Errorf("%v: error is %q, not %q", test.args, first, test.err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// replHelp describes the commands of the repl
+const replHelp = `each line adds steps in the DSL, as: find "foo" / maybe "s" / capture word
+blocks without a colon go on until a line "end"
+commands:
+  undo       remove the steps of the last line
+  reset      remove all the steps
+  steps      print the lines of the steps
+  load file  match the lines of file
+  help       print this help
+  quit       leave, as the end of the input`
+
+// runRepl builds an expression line by line, printing its regular
+// expression, its explanation and its matches in a corpus after each line
+func runRepl(c *cli, args []string) error {
+	fs := c.flags("repl", "[-corpus file] [-max n]")
+	corpus := fs.String("corpus", "", "file of sample lines matched by the expression")
+	max := fs.Int("max", 10, "maximum number of matching lines printed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("unexpected arguments: " + strings.Join(fs.Args(), " "))
+	}
+	r := &repl{c: c, max: *max, v: verbalexpressions.New()}
+	if *corpus != "" {
+		if err := r.load(*corpus); err != nil {
+			return err
+		}
+	}
+	return r.run()
+}
+
+// repl is the state of a repl session
+type repl struct {
+	c      *cli
+	max    int      // matching lines printed
+	lines  []string // the DSL of the steps, a line or a block each
+	v      *verbalexpressions.VerbalExpression
+	corpus []string // lines matched by v
+}
+
+// run reads the lines of the standard input until its end or "quit"
+func (r *repl) run() error {
+	scanner := bufio.NewScanner(r.c.stdin)
+	pending := "" // lines of a block without its end
+	for {
+		if pending == "" {
+			fmt.Fprint(r.c.stdout, "> ")
+		} else {
+			fmt.Fprint(r.c.stdout, "... ")
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(r.c.stdout)
+			return scanner.Err()
+		}
+		line := scanner.Text()
+		if pending != "" {
+			pending = r.add(pending + "\n" + line)
+			continue
+		}
+
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "undo":
+			if len(r.lines) == 0 {
+				fmt.Fprintln(r.c.stdout, "nothing to undo")
+				continue
+			}
+			r.lines = r.lines[:len(r.lines)-1]
+			r.v, _ = verbalexpressions.ParseDSL(strings.Join(r.lines, "\n"))
+			r.show()
+		case "reset":
+			r.lines, r.v = nil, verbalexpressions.New()
+			r.show()
+		case "steps":
+			for i, l := range r.lines {
+				fmt.Fprintf(r.c.stdout, "%d: %s\n", i+1, strings.ReplaceAll(l, "\n", "\n   "))
+			}
+		case "load":
+			if len(words) != 2 {
+				fmt.Fprintln(r.c.stdout, "usage: load file")
+				continue
+			}
+			if err := r.load(words[1]); err != nil {
+				fmt.Fprintf(r.c.stdout, "error: %v\n", err)
+				continue
+			}
+			r.show()
+		case "help":
+			fmt.Fprintln(r.c.stdout, replHelp)
+		case "quit", "exit":
+			return nil
+		default:
+			pending = r.add(line)
+		}
+	}
+}
+
+// add adds the steps of text and prints the new expression. It returns
+// text if its block isn't ended yet.
+func (r *repl) add(text string) string {
+	lines := append(r.lines[:len(r.lines):len(r.lines)], text)
+	v, err := verbalexpressions.ParseDSL(strings.Join(lines, "\n"))
+	if errors.Is(err, verbalexpressions.ErrMissingEnd) {
+		return text
+	}
+	if err == nil {
+		_, err = v.Compile()
+	}
+	var de *verbalexpressions.DSLError
+	switch {
+	case errors.As(err, &de):
+		// the lines before text are valid, errors are in text
+		line := de.Line
+		for _, l := range r.lines {
+			line -= strings.Count(l, "\n") + 1
+		}
+		if de.Text != "" {
+			fmt.Fprintf(r.c.stdout, "error: %d:%d: %s: %v\n", line, de.Column, de.Text, de.Err)
+		} else {
+			fmt.Fprintf(r.c.stdout, "error: %d:%d: %v\n", line, de.Column, de.Err)
+		}
+	case err != nil:
+		fmt.Fprintf(r.c.stdout, "error: %v\n", err)
+	default:
+		r.lines, r.v = lines, v
+		r.show()
+	}
+	return ""
+}
+
+// load reads the corpus from the file path
+func (r *repl) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	r.corpus = nil
+	if len(data) > 0 {
+		r.corpus = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	return nil
+}
+
+// show prints the regular expression, the explanation and the matches of
+// the expression
+func (r *repl) show() {
+	w := r.c.stdout
+	fmt.Fprintf(w, "regex: %s\n", r.v.Regex())
+	if explanation := r.v.Explain(); explanation != "" {
+		fmt.Fprintln(w, "  "+strings.ReplaceAll(explanation, "\n", "\n  "))
+	}
+	if r.corpus == nil {
+		return
+	}
+
+	names := r.v.Regex().SubexpNames()
+	matching, shown := 0, 0
+	var out strings.Builder
+	for i, line := range r.corpus {
+		matches := r.v.Captures(line)
+		if len(matches) == 0 {
+			continue
+		}
+		matching++
+		if shown == r.max {
+			continue
+		}
+		shown++
+		fmt.Fprintf(&out, "  %d: %s\n", i+1, line)
+		printMatches(&out, "    ", names, matches)
+	}
+	fmt.Fprintf(w, "matches: %d of %s\n", matching, plural(len(r.corpus), "line"))
+	fmt.Fprint(w, out.String())
+	if matching > shown {
+		fmt.Fprintf(w, "  %s more\n", plural(matching-shown, "matching line"))
+	}
+}
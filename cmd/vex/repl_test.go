@@ -0,0 +1,84 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+
+	dir := tree(t, map[string]string{"corpus.txt": "foos bar\nnothing\n"})
+	corpus := filepath.Join(dir, "corpus.txt")
+	out, errs, status := vex("find \"foo\"\ncapture word\nundo\nquit\nthen \"x\"\n", "repl", "-corpus", corpus)
+	expect := `> regex: (?m)foo
+  options: multiline
+  the text "foo"
+matches: 1 of 2 lines
+  1: foos bar
+    match 1: "foo"
+> regex: (?m)foo(\w+)
+  options: multiline
+  the text "foo"
+  capture group 1:
+    a word character, one or more times
+matches: 1 of 2 lines
+  1: foos bar
+    match 1: "foos"
+      1: "s"
+> regex: (?m)foo
+  options: multiline
+  the text "foo"
+matches: 1 of 2 lines
+  1: foos bar
+    match 1: "foo"
+> `
+	if out != expect || errs != "" || status != 0 {
+		t.Errorf("output is\n%s\nnot\n%s\nerrors %q, status %d", out, expect, errs, status)
+	}
+}
+
+func TestReplSteps(t *testing.T) {
+
+	in := "digit\nfrobnicate\ngroup\n\tletter\n\tthen 3\nundo\nundo\nundo\neither\n\tthen \"a\"\nor\n\tthen \"b\"\nend\nsteps\n"
+	out, _, _ := vex(in, "repl")
+	for _, expect := range []string{
+		"> regex: (?m)\\d\n",
+		"> error: 1:1: frobnicate: unknown step\n",
+		"> ... ... error: 3:7: 3: invalid step arguments\n",
+		"> regex: (?m)\n  options: multiline\n> nothing to undo\n",
+		"> nothing to undo\n",
+		"> ... ... ... ... regex: (?m)a|b\n",
+		"> 1: either\n   \tthen \"a\"\n   or\n   \tthen \"b\"\n   end\n",
+	} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("%q isn't in\n%s", expect, out)
+		}
+	}
+}
+
+func TestReplCorpus(t *testing.T) {
+
+	dir := tree(t, map[string]string{"a.txt": "1\n2\n3\nx\n", "b.txt": "y\n"})
+	in := "load " + filepath.Join(dir, "b.txt") + "\nload\nload " + filepath.Join(dir, "none.txt") + "\n"
+	out, _, _ := vex("digit\nreset\n"+in, "repl", "-max", "2", "-corpus", filepath.Join(dir, "a.txt"))
+	for _, expect := range []string{
+		"matches: 3 of 4 lines\n  1: 1\n    match 1: \"1\"\n  2: 2\n    match 1: \"2\"\n  1 matching line more\n",
+		"> regex: (?m)\n  options: multiline\nmatches: 4 of 4 lines\n",
+		"> regex: (?m)\n  options: multiline\nmatches: 1 of 1 line\n",
+		"> usage: load file\n",
+		"> error: open ",
+	} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("%q isn't in\n%s", expect, out)
+		}
+	}
+
+	_, errs, status := vex("", "repl", "-corpus", filepath.Join(dir, "none.txt"))
+	if status != 2 || !strings.HasPrefix(errs, "vex repl: open ") {
+		t.Errorf("errors %q, status %d", errs, status)
+	}
+}
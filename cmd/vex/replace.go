@@ -0,0 +1,167 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// runReplace replaces the matches of the expression in files. The status
+// is 1 if nothing is replaced, 2 if a file can't be read or written.
+func runReplace(c *cli, args []string) error {
+	fs := c.flags("replace", "(-e text | -f file | -r regex) -with template [-dry-run | -in-place [-backup suffix]] [-j n] [path...]")
+	var s source
+	s.add(fs)
+	with := fs.String("with", "", "replacement, with $1 or ${name} for capture groups")
+	dryRun := fs.Bool("dry-run", false, "print the unified diff of the changes, files are left unchanged")
+	inPlace := fs.Bool("in-place", false, "rewrite the files")
+	backup := fs.String("backup", "", "with -in-place, keep the original of rewritten files with this suffix")
+	workers := fs.Int("j", runtime.NumCPU(), "number of files replaced at the same time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case *dryRun && *inPlace:
+		return errors.New("-dry-run and -in-place can't be used together")
+	case *backup != "" && !*inPlace:
+		return errors.New("-backup needs -in-place")
+	case *inPlace && fs.NArg() == 0:
+		return errors.New("-in-place needs files")
+	case *workers < 1:
+		return fmt.Errorf("invalid -j %d", *workers)
+	}
+	v, err := s.load(c)
+	if err != nil {
+		return err
+	}
+	r := &replacer{v: v, with: *with, dryRun: *dryRun, inPlace: *inPlace, backup: *backup}
+
+	c.status = 1
+	total, files := 0, 0
+	report := func(res result) {
+		c.stdout.Write(res.out)
+		if res.err != nil {
+			fmt.Fprintf(c.stderr, "vex replace: %v\n", res.err)
+			c.status = 2
+			return
+		}
+		if res.count == 0 {
+			return
+		}
+		fmt.Fprintf(c.stderr, "%s: %s\n", res.name, plural(res.count, "replacement"))
+		total += res.count
+		files++
+		if c.status == 1 {
+			c.status = 0
+		}
+	}
+
+	if fs.NArg() == 0 {
+		data, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return err
+		}
+		report(r.replace("<stdin>", "", data, 0))
+		return nil
+	}
+	for res := range walk(fs.Args(), *workers, r.file) {
+		report(res)
+	}
+	fmt.Fprintf(c.stderr, "%s in %s\n", plural(total, "replacement"), plural(files, "file"))
+	return nil
+}
+
+// plural returns n and word, with an s if n is not 1
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// replacer replaces the matches of v in files
+type replacer struct {
+	v       *verbalexpressions.VerbalExpression
+	with    string // template of Replace()
+	dryRun  bool   // print diffs instead of the replaced text
+	inPlace bool   // rewrite files instead of printing the replaced text
+	backup  string // suffix of the copies of rewritten files, if any
+}
+
+// file replaces the matches in the file path, binary files are skipped. If
+// path is a link, its target is rewritten.
+func (r *replacer) file(path string) result {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return result{err: err}
+	}
+	data, err := os.ReadFile(target)
+	if err != nil || binary(data) {
+		return result{err: err}
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return result{err: err}
+	}
+	return r.replace(path, target, data, info.Mode().Perm())
+}
+
+// replace replaces the matches in data, the content of file name, and
+// rewrites the file target with mode perm if r.inPlace is set
+func (r *replacer) replace(name, target string, data []byte, perm fs.FileMode) result {
+	src := string(data)
+	// as Replace(), all the matches are replaced, even with StopAtFirst(true)
+	count := len(r.v.Regex().FindAllStringIndex(src, -1))
+	dst := r.v.Replace(src, r.with)
+
+	res := result{name: name, count: count}
+	switch {
+	case r.dryRun:
+		res.out = []byte(unifiedDiff(name, src, dst))
+	case !r.inPlace:
+		res.out = []byte(dst)
+	case count > 0:
+		if r.backup != "" {
+			res.err = writeFile(target+r.backup, data, perm)
+		}
+		if res.err == nil {
+			res.err = writeFile(target, []byte(dst), perm)
+		}
+	}
+	return res
+}
+
+// writeFile writes data to the file path atomically: data is written to a
+// temporary file of the same directory, renamed to path once complete
+func writeFile(path string, data []byte, perm fs.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".vex-*")
+	if err != nil {
+		return err
+	}
+	// fails once renamed
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,136 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// version is an expression for the versions of the replace tests
+const version = "then \"v\"\ncapture as major: digit\nthen \".\"\ncapture as minor: digit"
+
+func TestReplace(t *testing.T) {
+
+	out, errs, status := vex("use v1.2 and v3.4\n", "replace", "-e", version, "-with", "${major}-$minor")
+	if out != "use 1-2 and 3-4\n" || errs != "<stdin>: 2 replacements\n" || status != 0 {
+		t.Errorf("output %q, errors %q, status %d", out, errs, status)
+	}
+
+	// as Replace(), StopAtFirst(true) doesn't matter
+	out, _, _ = vex("v1.2 v3.4", "replace", "-e", "stop at first\n"+version, "-with", "x")
+	if out != "x x" {
+		t.Errorf("output is %q", out)
+	}
+
+	out, errs, status = vex("nothing\n", "replace", "-e", version, "-with", "x")
+	if out != "nothing\n" || errs != "" || status != 1 {
+		t.Errorf("output %q, errors %q, status %d", out, errs, status)
+	}
+}
+
+func TestReplaceDryRun(t *testing.T) {
+
+	dir := tree(t, map[string]string{"a.txt": "v1.2\nsame\n", "b.txt": "none\n"})
+	out, errs, status := vex("", "replace", "-dry-run", "-e", version, "-with", "v$major.x", dir)
+	a := filepath.Join(dir, "a.txt")
+	expect := "--- " + a + "\n+++ " + a + "\n@@ -1,2 +1,2 @@\n-v1.2\n+v1.x\n same\n"
+	if out != expect || status != 0 {
+		t.Errorf("output is\n%s\nnot\n%s, status %d", out, expect, status)
+	}
+	if errs != a+": 1 replacement\n1 replacement in 1 file\n" {
+		t.Errorf("summary is %q", errs)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "v1.2\nsame\n" {
+		t.Errorf("file is changed: %q", data)
+	}
+}
+
+func TestReplaceInPlace(t *testing.T) {
+
+	dir := tree(t, map[string]string{"a.txt": "v1.2 v1.3\n", "sub/b.txt": "v2.0\n", "c.txt": "none\n"})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "sub", "b.txt")
+	if err := os.Chmod(a, 0600); err != nil {
+		t.Fatal(err)
+	}
+	out, errs, status := vex("", "replace", "-in-place", "-backup", ".orig", "-j", "2", "-e", version, "-with", "v${major}", dir)
+	if out != "" || status != 0 {
+		t.Errorf("output %q, status %d", out, status)
+	}
+	expect := a + ": 2 replacements\n" + b + ": 1 replacement\n3 replacements in 2 files\n"
+	if errs != expect {
+		t.Errorf("summary is\n%s\nnot\n%s", errs, expect)
+	}
+
+	files := map[string]string{
+		a:           "v1 v1\n",
+		a + ".orig": "v1.2 v1.3\n",
+		b:           "v2\n",
+		b + ".orig": "v2.0\n",
+	}
+	for name, content := range files {
+		if data, err := os.ReadFile(name); err != nil || string(data) != content {
+			t.Errorf("%s is %q, not %q (%v)", name, data, content, err)
+		}
+	}
+	if info, err := os.Stat(a); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode of %s is %v (%v)", a, info.Mode(), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c.txt.orig")); err == nil {
+		t.Error("unchanged file has a backup")
+	}
+
+	// no temporary file is left
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			t.Errorf("%s is left", e.Name())
+		}
+	}
+}
+
+func TestReplaceLinks(t *testing.T) {
+
+	dir := tree(t, map[string]string{"a.txt": "v1.2\n"})
+	a, link := filepath.Join(dir, "a.txt"), filepath.Join(dir, "link.txt")
+	if err := os.Symlink("a.txt", link); err != nil {
+		t.Skip(err)
+	}
+	_, errs, status := vex("", "replace", "-in-place", "-backup", ".orig", "-e", version, "-with", "v$major", link)
+	if errs != link+": 1 replacement\n1 replacement in 1 file\n" || status != 0 {
+		t.Errorf("errors %q, status %d", errs, status)
+	}
+
+	// the link is kept, its target is rewritten
+	if target, err := os.Readlink(link); err != nil || target != "a.txt" {
+		t.Errorf("link is %q (%v)", target, err)
+	}
+	files := map[string]string{a: "v1\n", a + ".orig": "v1.2\n"}
+	for name, content := range files {
+		if data, err := os.ReadFile(name); err != nil || string(data) != content {
+			t.Errorf("%s is %q, not %q (%v)", name, data, content, err)
+		}
+	}
+}
+
+func TestReplaceErrors(t *testing.T) {
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"-dry-run", "-in-place", "f"}, "vex replace: -dry-run and -in-place can't be used together"},
+		{[]string{"-backup", ".orig", "f"}, "vex replace: -backup needs -in-place"},
+		{[]string{"-in-place"}, "vex replace: -in-place needs files"},
+	}
+	for _, test := range tests {
+		args := append([]string{"replace", "-e", "digit", "-with", "x"}, test.args...)
+		_, errs, status := vex("", args...)
+		if status != 2 || errs != test.err+"\n" {
+			t.Errorf("%v: errors %q, status %d", test.args, errs, status)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// static are the files of the web page, served without network access
+//
+//go:embed static
+var static embed.FS
+
+// runServe serves the playground, a web page matching expressions against
+// sample text, and its JSON API
+func runServe(c *cli, args []string) error {
+	flags := c.flags("serve", "[-addr host:port]")
+	addr := flags.String("addr", "localhost:8080", "address to listen on, on the loopback interface")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return errors.New("unexpected arguments: " + strings.Join(flags.Args(), " "))
+	}
+	host, _, err := net.SplitHostPort(*addr)
+	if err != nil {
+		return err
+	}
+	if !loopback(host) {
+		return fmt.Errorf("%s is not a loopback address", host)
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "vex serve: listening on http://%s\n", ln.Addr())
+	return http.Serve(ln, playground())
+}
+
+// loopback tells if host is a name or an address of the loopback interface
+func loopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// playground returns the handler of the web page and of the API
+func playground() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	mux.HandleFunc("/api/match", serveMatch)
+	return localOnly(mux)
+}
+
+// localOnly rejects the requests for another host than the loopback
+// interface, as pages of other sites resolving their name to it
+func localOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !loopback(strings.Trim(host, "[]")) {
+			http.Error(w, "vex serve only answers to localhost", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// matchRequest is the body of a request of /api/match
+type matchRequest struct {
+	Expression string `json:"expression"` // in the DSL or in JSON
+	Regex      string `json:"regex"`      // a regular expression, instead of expression
+	Text       string `json:"text"`       // the sample text
+}
+
+// matchResponse is the body of a response of /api/match
+type matchResponse struct {
+	Regex   string   `json:"regex"`
+	Explain string   `json:"explain"`
+	DSL     string   `json:"dsl"`
+	Groups  []string `json:"groups"`  // names of the capture groups, from 1, "" if unnamed
+	Matches [][]span `json:"matches"` // the match, then its capture groups, as Captures()
+}
+
+// span is the text of a match or of a capture group, between byte offsets
+// start and end of the sample text, -1 if the group didn't match
+type span struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// matchError is the body of the response to an invalid request, with the
+// position of the error in the DSL, if any
+type matchError struct {
+	Error  string `json:"error"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// serveMatch matches the expression of the request against its text
+func serveMatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, matchError{Error: "use POST"})
+		return
+	}
+	var req matchRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, matchError{Error: err.Error()})
+		return
+	}
+
+	var v *verbalexpressions.VerbalExpression
+	var err error
+	switch {
+	case req.Regex != "":
+		v, err = verbalexpressions.FromRegex(req.Regex)
+	case strings.HasPrefix(strings.TrimSpace(req.Expression), "{"):
+		v = verbalexpressions.New()
+		err = v.UnmarshalJSON([]byte(req.Expression))
+	default:
+		v, err = verbalexpressions.ParseDSL(req.Expression)
+	}
+	if err == nil {
+		_, err = v.Compile()
+	}
+	if err != nil {
+		res := matchError{Error: strings.TrimPrefix(err.Error(), "verbalexpressions: ")}
+		var de *verbalexpressions.DSLError
+		if errors.As(err, &de) {
+			res.Line, res.Column = de.Line, de.Column
+		}
+		writeJSON(w, http.StatusBadRequest, res)
+		return
+	}
+
+	res := matchResponse{
+		Regex:   v.Regex().String(),
+		Explain: v.Explain(),
+		DSL:     v.DSL(),
+		Groups:  v.Regex().SubexpNames()[1:],
+		Matches: [][]span{},
+	}
+	for _, m := range v.CapturesIndex(req.Text) {
+		spans := make([]span, len(m)/2)
+		for i := range spans {
+			spans[i] = span{Start: m[2*i], End: m[2*i+1]}
+			if m[2*i] >= 0 {
+				spans[i].Text = req.Text[m[2*i]:m[2*i+1]]
+			}
+		}
+		res.Matches = append(res.Matches, spans)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// writeJSON writes the response v with status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
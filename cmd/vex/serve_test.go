@@ -0,0 +1,102 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// post posts body to /api/match of the playground
+func post(body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "http://localhost:8080/api/match", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	playground().ServeHTTP(w, r)
+	return w
+}
+
+func TestServeMatch(t *testing.T) {
+
+	tests := []struct {
+		body   string
+		status int
+		expect string
+	}{
+		{
+			`{"expression": "then \"a\"\ncapture as n: digit", "text": "a1 é a2"}`,
+			http.StatusOK,
+			`{"regex":"(?m)a(?P\u003cn\u003e\\d)","explain":"options: multiline\nthe text \"a\"\ncapture group 1 \"n\":\n  a digit",` +
+				`"dsl":"then \"a\"\ncapture as n: digit\n","groups":["n"],` +
+				`"matches":[[{"start":0,"end":2,"text":"a1"},{"start":1,"end":2,"text":"1"}],[{"start":6,"end":8,"text":"a2"},{"start":7,"end":8,"text":"2"}]]}`,
+		},
+		{
+			`{"regex": "a(x)?", "text": "ax a"}`,
+			http.StatusOK,
+			`{"regex":"(?m)a(x)?","explain":"options: multiline\nthe text \"a\"\noptional:\n  capture group 1:\n    the text \"x\"",` +
+				`"dsl":"then \"a\"\ncapture: then \"x\"\noptional\n","groups":[""],` +
+				`"matches":[[{"start":0,"end":2,"text":"ax"},{"start":1,"end":2,"text":"x"}],[{"start":3,"end":4,"text":"a"},{"start":-1,"end":-1,"text":""}]]}`,
+		},
+		{
+			`{"expression": "{\"steps\":[{\"step\":\"Find\",\"args\":[\"a\"]}]}", "text": "b"}`,
+			http.StatusOK,
+			`{"regex":"(?m)a","explain":"options: multiline\nthe text \"a\"","dsl":"then \"a\"\n","groups":[],"matches":[]}`,
+		},
+		{
+			`{"expression": "digit\nfrobnicate"}`,
+			http.StatusBadRequest,
+			`{"error":"line 2, column 1: frobnicate: unknown step","line":2,"column":1}`,
+		},
+		{`{"regex": "("}`, http.StatusBadRequest, `{"error":"error parsing regexp: missing closing ): ` + "`(`" + `"}`},
+		{`{"text": "a", "flags": 1}`, http.StatusBadRequest, `{"error":"json: unknown field \"flags\""}`},
+	}
+	for _, test := range tests {
+		w := post(test.body)
+		if w.Code != test.status || strings.TrimSpace(w.Body.String()) != test.expect {
+			t.Errorf("%s: status %d, response\n%s\nnot\n%s", test.body, w.Code, w.Body, test.expect)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: content type is %q", test.body, ct)
+		}
+	}
+}
+
+func TestServePage(t *testing.T) {
+
+	for path, expect := range map[string]string{
+		"/":          "<title>vex playground</title>",
+		"/app.js":    "api/match",
+		"/style.css": "mark",
+	} {
+		w := httptest.NewRecorder()
+		playground().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://127.0.0.1:8080"+path, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), expect) {
+			t.Errorf("%s: status %d, %q isn't in\n%s", path, w.Code, expect, w.Body)
+		}
+	}
+
+	// another site resolving its name to localhost
+	w := httptest.NewRecorder()
+	playground().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("request of example.com: status %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	playground().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://[::1]:8080/api/match", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET of the API: status %d", w.Code)
+	}
+}
+
+func TestServeAddr(t *testing.T) {
+
+	for _, addr := range []string{"0.0.0.0:8080", "example.com:80", ":8080"} {
+		_, errs, status := vex("", "serve", "-addr", addr)
+		if status != 2 || !strings.Contains(errs, "is not a loopback address") {
+			t.Errorf("%s: errors %q, status %d", addr, errs, status)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// the playground sends the expression and the sample text to /api/match
+// after each change, and shows the matches of the response
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+const encoder = new TextEncoder();
+const decoder = new TextDecoder();
+let timer = 0;
+let pending = null;
+
+function mode() {
+	return document.querySelector("input[name=mode]:checked").value;
+}
+
+function update() {
+	clearTimeout(timer);
+	timer = setTimeout(send, 150);
+}
+
+async function send() {
+	if (pending) {
+		pending.abort();
+	}
+	pending = new AbortController();
+	const body = { text: $("text").value };
+	body[mode()] = $("expression").value;
+	let res;
+	try {
+		const r = await fetch("api/match", {
+			method: "POST",
+			headers: { "Content-Type": "application/json" },
+			body: JSON.stringify(body),
+			signal: pending.signal,
+		});
+		res = await r.json();
+	} catch (e) {
+		if (e.name !== "AbortError") {
+			$("error").textContent = String(e);
+		}
+		return;
+	}
+	if (res.error !== undefined) {
+		$("error").textContent = res.error;
+		return;
+	}
+	$("error").textContent = "";
+	show(res);
+}
+
+// show shows the response, offsets of matches are in bytes of UTF-8
+function show(res) {
+	$("regex").textContent = res.regex;
+	$("explain").textContent = res.explain;
+	$("count").textContent = "(" + res.matches.length + ")";
+
+	const bytes = encoder.encode($("text").value);
+	const text = (start, end) => decoder.decode(bytes.slice(start, end));
+	const highlight = $("highlight");
+	highlight.replaceChildren();
+	let pos = 0;
+	for (const m of res.matches) {
+		highlight.append(text(pos, m[0].start));
+		const mark = document.createElement("mark");
+		mark.textContent = m[0].text;
+		if (m[0].text === "") {
+			mark.className = "empty";
+		}
+		highlight.append(mark);
+		pos = m[0].end;
+	}
+	highlight.append(text(pos, bytes.length));
+
+	const table = $("matches");
+	table.replaceChildren();
+	const head = table.insertRow();
+	for (const name of ["match"].concat(res.groups.map((g, i) => (i + 1) + (g ? " " + g : "")))) {
+		const th = document.createElement("th");
+		th.textContent = name;
+		head.append(th);
+	}
+	for (const m of res.matches) {
+		const row = table.insertRow();
+		for (const s of m) {
+			row.insertCell().textContent = s.start < 0 ? "-" : JSON.stringify(s.text);
+		}
+	}
+}
+
+$("expression").addEventListener("input", update);
+$("text").addEventListener("input", update);
+for (const input of document.querySelectorAll("input[name=mode]")) {
+	input.addEventListener("change", update);
+}
+send();
@@ -0,0 +1,115 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// result is the output of a command for a file
+type result struct {
+	name  string // the file
+	out   []byte // printed on the standard output
+	count int    // matches found
+	err   error
+}
+
+// walk calls do for the files of paths, walking directories, with workers
+// files at the same time. Results are sent in the order of the paths. Links
+// and other files that are not regular are skipped in directories, paths
+// that are not regular files or directories are errors.
+func walk(paths []string, workers int, do func(path string) result) <-chan result {
+	type job struct {
+		path string
+		done chan result
+	}
+	jobs := make(chan job)
+	// order has the results of files being searched, as they are found
+	order := make(chan chan result, workers)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r := do(j.path)
+				r.name = j.path
+				j.done <- r
+			}
+		}()
+	}
+
+	go func() {
+		send := func(j job, err error) {
+			j.done = make(chan result, 1)
+			order <- j.done
+			if err != nil {
+				j.done <- result{name: j.path, err: err}
+				return
+			}
+			jobs <- j
+		}
+		for _, root := range paths {
+			// links given as paths are followed, not the links found in
+			// directories
+			info, err := os.Stat(root)
+			switch {
+			case err != nil:
+				send(job{path: root}, err)
+				continue
+			case info.Mode().IsRegular():
+				send(job{path: root}, nil)
+				continue
+			case !info.IsDir():
+				send(job{path: root}, fmt.Errorf("%s: not a regular file or a directory", root))
+				continue
+			}
+			dir, err := filepath.EvalSymlinks(root)
+			if err != nil {
+				send(job{path: root}, err)
+				continue
+			}
+			filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				// named from root, as given
+				if rel, rerr := filepath.Rel(dir, path); rerr == nil {
+					path = filepath.Join(root, rel)
+				}
+				switch {
+				case err != nil:
+					send(job{path: path}, err)
+				case d.Type().IsRegular():
+					send(job{path: path}, nil)
+				}
+				return nil
+			})
+		}
+		close(jobs)
+		close(order)
+	}()
+
+	go func() {
+		for done := range order {
+			results <- <-done
+		}
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// binary tells if a file beginning with head is binary, with a NUL byte in
+// its first 512 bytes
+func binary(head []byte) bool {
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
@@ -0,0 +1,172 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+
+// Vexgen generates Go code declaring verbal expressions from regular
+// expressions, to be used with go generate:
+//
+//	//go:generate vexgen -o patterns_vex.go patterns.vex
+//
+// Each line of the definition file names a regular expression:
+//
+//	# comments and empty lines are ignored
+//	Email = [\w.-]+@[\w-]+\.\w+
+//	Date  = \d{4}-\d{2}-\d{2}
+//
+// Spaces around the name and the pattern are removed. Names are Go
+// identifiers, other than regexp and verbalexpressions, the packages
+// imported by the generated file. For each definition, the generated file
+// declares the VerbalExpression built with the fluent methods, Email, and
+// the compiled regexp, EmailRegexp. Expressions are immutable, so they can
+// be extended without modifying the shared value.
+//
+// Invalid patterns are reported when the code is generated, with the line of
+// the definition, instead of when the program runs.
+//
+// Usage:
+//
+//	vexgen [-o output] [-pkg name] file
+//
+// The output defaults to the definition file name with a "_vex.go" suffix,
+// the package name to $GOPACKAGE, set by go generate.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("vexgen: ")
+
+	output := flag.String("o", "", "output file, default is the input file with a _vex.go suffix")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated file, default is $GOPACKAGE")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: vexgen [-o output] [-pkg name] file")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *pkg == "" {
+		log.Fatal("no package name, use -pkg or run with go generate")
+	}
+	input := flag.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + "_vex.go"
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	src, err := generate(f, filepath.Base(input), *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// definition is a named regular expression of the input file
+type definition struct {
+	name    string
+	pattern string
+	v       *verbalexpressions.VerbalExpression
+}
+
+// imports are the packages used by the generated file, definitions can't
+// have their names
+var imports = map[string]bool{"regexp": true, "verbalexpressions": true}
+
+// parse reads definitions, errors are reported with the line number
+func parse(r io.Reader, filename string) ([]definition, error) {
+	defs := []definition{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, pattern, ok := strings.Cut(text, "=")
+		name, pattern = strings.TrimSpace(name), strings.TrimSpace(pattern)
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("%s:%d: missing \"=\" between name and pattern", filename, line)
+		case !token.IsIdentifier(name):
+			return nil, fmt.Errorf("%s:%d: %q is not a Go identifier", filename, line, name)
+		case imports[name]:
+			return nil, fmt.Errorf("%s:%d: %s is the name of an imported package", filename, line, name)
+		case seen[name] || seen[name+"Regexp"]:
+			return nil, fmt.Errorf("%s:%d: %s is already defined", filename, line, name)
+		}
+
+		v, err := verbalexpressions.FromRegex(pattern)
+		if err == nil {
+			_, err = v.Compile()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %v", filename, line, name, err)
+		}
+		seen[name], seen[name+"Regexp"] = true, true
+		defs = append(defs, definition{name: name, pattern: pattern, v: v})
+	}
+	return defs, scanner.Err()
+}
+
+// generate returns the formatted Go file declaring definitions read from r
+func generate(r io.Reader, filename, pkg string) ([]byte, error) {
+	defs, err := parse(r, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by vexgen from %s. DO NOT EDIT.\n\n", filename)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	if len(defs) > 0 {
+		b.WriteString("import (\n\t\"regexp\"\n\n\t\"github.com/VerbalExpressions/GoVerbalExpressions\"\n)\n")
+	}
+	for _, d := range defs {
+		source := d.v.GoSource()
+		if strings.Contains(source, "\n") {
+			source += ".\n\tImmutable()"
+		} else {
+			source += ".Immutable()"
+		}
+		fmt.Fprintf(&b, "\n// %s matches `%s`\n", d.name, d.pattern)
+		fmt.Fprintf(&b, "var %s = %s\n", d.name, source)
+		fmt.Fprintf(&b, "\n// %sRegexp is %s compiled\n", d.name, d.name)
+		fmt.Fprintf(&b, "var %sRegexp = regexp.MustCompile(%s)\n", d.name, quote(d.v.String()))
+	}
+	return format.Source(b.Bytes())
+}
+
+// quote returns s as a Go string, in back quotes if possible
+func quote(s string) string {
+	if strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
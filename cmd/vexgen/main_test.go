@@ -0,0 +1,90 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+
+	input := `# test patterns
+Date = \d{4}-\d{2}-\d{2}
+
+Word=^\w+$
+`
+	src, err := generate(strings.NewReader(input), "patterns.vex", "patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "// Code generated by vexgen from patterns.vex. DO NOT EDIT.\n" +
+		`
+package patterns
+
+import (
+	"regexp"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// Date matches '\d{4}-\d{2}-\d{2}'
+var Date = verbalexpressions.New().
+	Digit().
+	Times(4).
+	Find("-").
+	Digit().
+	Times(2).
+	Find("-").
+	Digit().
+	Times(2).
+	Immutable()
+
+// DateRegexp is Date compiled
+var DateRegexp = regexp.MustCompile('(?m)\d{4}-\d{2}-\d{2}')
+
+// Word matches '^\w+$'
+var Word = verbalexpressions.New().
+	SearchOneLine(true).
+	StartOfLine().
+	Word().
+	EndOfLine().
+	Immutable()
+
+// WordRegexp is Word compiled
+var WordRegexp = regexp.MustCompile('^\w+$')
+`
+	// back quotes can't be written in the raw string
+	expect = strings.ReplaceAll(expect, "'", "`")
+	if string(src) != expect {
+		t.Errorf("generated code is\n%s\nnot\n%s", src, expect)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "patterns_vex.go", src, 0); err != nil {
+		t.Errorf("generated code is not valid Go: %v", err)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+
+	tests := []struct {
+		input, err string
+	}{
+		{"A = a\nB = a(b", "patterns.vex:2: B: error parsing regexp: missing closing ): `a(b`"},
+		{"\n\nA a", `patterns.vex:3: missing "=" between name and pattern`},
+		{"my-name = a", `patterns.vex:1: "my-name" is not a Go identifier`},
+		{"A = a\nA = b", "patterns.vex:2: A is already defined"},
+		{"A = a\nARegexp = b", "patterns.vex:2: ARegexp is already defined"},
+		{"regexp = a", "patterns.vex:1: regexp is the name of an imported package"},
+		{"verbalexpressions = a", "patterns.vex:1: verbalexpressions is the name of an imported package"},
+	}
+	for _, test := range tests {
+		_, err := generate(strings.NewReader(test.input), "patterns.vex", "patterns")
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: error is %v, not %s", test.input, err, test.err)
+		}
+	}
+}
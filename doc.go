@@ -23,11 +23,25 @@ Then, you can use "Test()" method to check if your string matches expression.
 
 You may get the regexp.Regexp structure using "Regex()" method, then use common methods to split, replace, find submatches and so on... as usual
 
+Builder methods never panic. If a method receives bad arguments, the error is recorded and returned by "Err()". Use "Compile()" to get the regexp.Regexp structure or that error, "Regex()" panics if the expression is not valid:
+
+	r, err := verbalexpressions.New().Range(from, to).Compile()
+	if err != nil {
+		// err is a *StepError telling which method failed, and with which arguments
+	}
+
 There are some helpers that use direct call to the regexp package:
 
 - Replace
 - Captures
 - Test
 
+Builder methods modify the expression and return it. To reuse a partial expression, get a copy with "Clone()", or use "Immutable()" so that each method returns a new expression:
+
+	url := verbalexpressions.New().Immutable().StartOfLine().Find("http")
+	secure := url.Find("s://") // url is still "^http"
+
+The regular expression is compiled on first use and kept until a builder method modifies the expression. A built expression can be shared between goroutines: Regex, Compile, Test, Replace and Captures are safe for concurrent use. Builder methods are not, don't call them while the expression is in use.
+
 */
 package verbalexpressions
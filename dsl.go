@@ -0,0 +1,705 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* a text language for expressions, one builder step per line */
+
+// ParseDSL returns the expression described by src, a text with one step
+// per line, or several steps separated by "/" on a line:
+//
+//	# an url
+//	start of line
+//	then "http" / maybe "s" / then "://"
+//	capture as host: word
+//
+// Steps are the builder methods written in lower case words, "start of
+// line" for StartOfLine(), followed by their arguments: strings and runes
+// in Go syntax ("abc", `\d+`, 'a'), integers, true or false. Arguments are
+// separated by commas, "and" or "to", as in between 1 and 3 or range "a" to
+// "z". A true bool argument can be left out: with any case.
+//
+// Methods taking a function, an expression or a class, like Group(),
+// Either() or Class(), are followed by a block of steps ending with "end",
+// or by the steps of the rest of the line, after an optional colon:
+//
+//	either
+//		then "cat"
+//	or
+//		then "dog"
+//	end
+//	class: letter / chars "_-"
+//	capture word
+//
+// A line "or" starts an alternative, as Or() does, or the next expression
+// of Either(). "capture" and "capture as name" are blocks of steps between
+// BeginCapture() or BeginNamedCapture() and EndCapture().
+//
+// Errors are *DSLError, with the line and the column of the faulty text.
+// DSL() returns the text of an expression.
+func ParseDSL(src string) (*VerbalExpression, error) {
+	tokens, err := dslTokens(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &dslParser{tokens: tokens}
+	alts, err := p.list(expressionType, endSource, dslToken{})
+	if err != nil {
+		return nil, err
+	}
+
+	steps := alts[0].steps
+	for _, alt := range alts[1:] {
+		body, err := nested(alt.steps)
+		if err != nil {
+			return nil, err
+		}
+		or := step{name: "Or", args: []interface{}{chain{flags: MULTILINE | GLOBAL, steps: body}}}
+		steps = append(steps, posStep{or, alt.at})
+	}
+
+	// steps are flat, captures are ended by their EndCapture() step
+	v := New()
+	for _, s := range steps {
+		v = call(v, s.name, s.args).(*VerbalExpression)
+		if err := v.Err(); err != nil {
+			if se, ok := err.(*StepError); ok {
+				err = se.Err
+			}
+			return nil, s.at.fail(err)
+		}
+	}
+	return v, nil
+}
+
+// DSL returns the text of the expression in the language of ParseDSL(), for
+// example:
+//
+//	start of line
+//	then "http"
+//	maybe "s"
+//	then "://"
+//	capture as host: word
+//
+// The text may differ from the steps that built the expression, but it
+// makes the same regular expression. Steps that failed are not part of it,
+// see Err().
+func (v *VerbalExpression) DSL() string {
+	c := v.chain()
+	lines := dslLines(append(flagSteps(c.flags), c.steps...))
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// dslKind is the kind of a token
+type dslKind int
+
+const (
+	tokWord dslKind = iota
+	tokString
+	tokRune
+	tokInt
+	tokPunct // one of , : /
+	tokNewline
+	tokEOF
+)
+
+// dslToken is a word, a value or a punctuation of the source
+type dslToken struct {
+	kind      dslKind
+	text      string
+	value     interface{} // string, rune or int
+	line, col int
+}
+
+// is tells if t is the punctuation p
+func (t dslToken) is(p string) bool {
+	return t.kind == tokPunct && t.text == p
+}
+
+// isWord tells if t is the word w
+func (t dslToken) isWord(w string) bool {
+	return t.kind == tokWord && t.text == w
+}
+
+// isValue tells if t is an argument
+func (t dslToken) isValue() bool {
+	return t.kind == tokString || t.kind == tokRune || t.kind == tokInt || t.isWord("true") || t.isWord("false")
+}
+
+// fail returns err at the position of t
+func (t dslToken) fail(err error) *DSLError {
+	return &DSLError{Line: t.line, Column: t.col, Text: t.text, Err: err}
+}
+
+// dslTokens splits src in tokens, with a tokNewline at the end of each line
+func dslTokens(src string) ([]dslToken, error) {
+	tokens := []dslToken{}
+	for n, line := range strings.Split(src, "\n") {
+		runes := []rune(line)
+		for i := 0; i < len(runes); {
+			r := runes[i]
+			t := dslToken{line: n + 1, col: i + 1}
+			start := i
+			i++
+			switch {
+			case r == '#':
+				i = len(runes)
+				continue
+			case unicode.IsSpace(r):
+				continue
+			case r == ',' || r == ':' || r == '/':
+				t.kind = tokPunct
+			case unicode.IsLetter(r) || r == '_':
+				for i < len(runes) && isWordRune(runes[i]) {
+					i++
+				}
+				t.kind = tokWord
+			case r == '-' || unicode.IsDigit(r):
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+				t.kind = tokInt
+			case r == '"' || r == '\'' || r == '`':
+				for i < len(runes) && runes[i] != r {
+					if runes[i] == '\\' && r != '`' {
+						i++
+					}
+					i++
+				}
+				i++
+				t.kind = tokString
+				if r == '\'' {
+					t.kind = tokRune
+				}
+			default:
+				t.text = string(r)
+				return nil, t.fail(ErrUnexpected)
+			}
+			if i > len(runes) {
+				i = len(runes)
+			}
+			t.text = string(runes[start:i])
+			if err := t.parseValue(); err != nil {
+				return nil, t.fail(err)
+			}
+			tokens = append(tokens, t)
+		}
+		tokens = append(tokens, dslToken{kind: tokNewline, line: n + 1, col: len(runes) + 1})
+	}
+	last := tokens[len(tokens)-1]
+	return append(tokens, dslToken{kind: tokEOF, line: last.line, col: last.col}), nil
+}
+
+// isWordRune tells if r is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// parseValue sets the value of string, rune and int tokens
+func (t *dslToken) parseValue() error {
+	var err error
+	switch t.kind {
+	case tokInt:
+		t.value, err = strconv.Atoi(t.text)
+	case tokString:
+		t.value, err = strconv.Unquote(t.text)
+	case tokRune:
+		var s string
+		s, err = strconv.Unquote(t.text)
+		t.value, _ = utf8.DecodeRuneInString(s)
+	}
+	if err != nil {
+		return ErrBadLiteral
+	}
+	return nil
+}
+
+// posStep is a step with the token where it starts
+type posStep struct {
+	step
+	at dslToken
+}
+
+// dslAlt is an alternative of a block, starting at token at
+type dslAlt struct {
+	at    dslToken
+	steps []posStep
+}
+
+// dslEnd is how a list of steps ends
+type dslEnd int
+
+const (
+	endSource dslEnd = iota // at the end of the source
+	endLine                 // at the end of the line, after a colon
+	endBlock                // at "end"
+)
+
+// dslParser returns the steps of tokens
+type dslParser struct {
+	tokens []dslToken
+	i      int
+}
+
+func (p *dslParser) peek() dslToken {
+	return p.tokens[p.i]
+}
+
+func (p *dslParser) next() dslToken {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// endsStep tells if the token after the next n ones ends a step
+func (p *dslParser) endsStep(n int) bool {
+	if p.i+n >= len(p.tokens) {
+		return true
+	}
+	t := p.tokens[p.i+n]
+	return t.kind == tokNewline || t.kind == tokEOF || t.is("/")
+}
+
+// list returns the steps of a block of methods of receiver, split at "or"
+// lines. Open is the token opening the block.
+func (p *dslParser) list(receiver reflect.Type, end dslEnd, open dslToken) ([]dslAlt, error) {
+	alts := []dslAlt{{at: open, steps: []posStep{}}}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokEOF:
+			if end == endBlock {
+				return nil, open.fail(ErrMissingEnd)
+			}
+			return alts, nil
+		case t.kind == tokNewline && end == endLine:
+			return alts, nil
+		case t.kind == tokNewline || t.is("/"):
+			p.next()
+			continue
+		case t.isWord("end") && p.endsStep(1):
+			if end != endBlock {
+				return nil, t.fail(ErrUnexpected)
+			}
+			p.next()
+			return alts, nil
+		case t.isWord("or") && p.endsStep(1):
+			p.next()
+			alts = append(alts, dslAlt{at: t, steps: []posStep{}})
+			continue
+		}
+
+		steps, err := p.step(receiver)
+		if err != nil {
+			return nil, err
+		}
+		last := &alts[len(alts)-1]
+		last.steps = append(last.steps, steps...)
+		if !p.endsStep(0) {
+			return nil, p.peek().fail(ErrUnexpected)
+		}
+	}
+}
+
+// step returns the steps of the next method of receiver, with its block
+func (p *dslParser) step(receiver reflect.Type) ([]posStep, error) {
+	start := p.peek()
+	if start.kind != tokWord || start.isValue() {
+		return nil, start.fail(ErrUnexpected)
+	}
+	words := []string{}
+	at := []int{} // token index of each word
+	args := []dslToken{}
+	for p.peek().kind == tokWord && !p.peek().isValue() {
+		at = append(at, p.i)
+		words = append(words, p.next().text)
+		if receiver == expressionType && strings.Join(words, " ") == "capture as" {
+			// the name may be a word
+			if name := p.peek(); name.kind == tokWord {
+				name.kind, name.value = tokString, name.text
+				args = append(args, name)
+				p.next()
+			}
+			break
+		}
+	}
+
+	name, capture, m, ok := dslResolve(receiver, words)
+	if !ok {
+		// a block step followed by a step on the line: capture word
+		for n := len(words) - 1; n > 0; n-- {
+			if name, capture, m, ok = dslResolve(receiver, words[:n]); ok && dslBlock(m, capture) != nil {
+				p.i = at[n]
+				words = words[:n]
+				break
+			}
+			ok = false
+		}
+	}
+	if !ok {
+		start.text = strings.Join(words, " ")
+		return nil, start.fail(ErrUnknownStep)
+	}
+	more, err := p.args()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, more...)
+	block := dslBlock(m, capture)
+
+	var alts []dslAlt
+	if p.peek().is(":") || block != nil {
+		if block == nil {
+			return nil, p.peek().fail(ErrUnexpected)
+		}
+		if !capture && len(args) > 0 {
+			return nil, args[0].fail(ErrStepArgs)
+		}
+		inner, end := expressionType, endBlock
+		if block == classType {
+			inner = classType
+		}
+		if p.peek().is(":") {
+			end = endLine
+			p.next()
+		} else if !p.endsStep(0) {
+			end = endLine
+		}
+		if alts, err = p.list(inner, end, start); err != nil {
+			return nil, err
+		}
+	}
+
+	s := step{name: name}
+	switch {
+	case capture:
+		return p.capture(s, args, alts, start)
+	case block != nil:
+		s.args, err = blockArgs(m.Type.IsVariadic(), block, alts)
+		if err != nil {
+			return nil, err
+		}
+		if !takesArgs(m, len(s.args)) {
+			return nil, start.fail(ErrStepArgs)
+		}
+		return []posStep{{s, start}}, nil
+	}
+
+	if len(args) == 0 && m.Type.NumIn() == 2 && paramType(m, 0).Kind() == reflect.Bool {
+		// true is the default of flag steps
+		s.args = []interface{}{true}
+	}
+	if !takesArgs(m, len(args)+len(s.args)) {
+		return nil, start.fail(ErrStepArgs)
+	}
+	for i, a := range args {
+		value, ok := dslValue(paramType(m, i), a)
+		if !ok {
+			return nil, a.fail(ErrStepArgs)
+		}
+		s.args = append(s.args, value)
+	}
+	return []posStep{{s, start}}, nil
+}
+
+// args returns the values after the words of a step
+func (p *dslParser) args() ([]dslToken, error) {
+	args := []dslToken{}
+	for p.peek().isValue() {
+		args = append(args, p.next())
+		if t := p.peek(); t.is(",") || t.isWord("and") || t.isWord("to") {
+			p.next()
+			if !p.peek().isValue() {
+				return nil, p.peek().fail(ErrUnexpected)
+			}
+		}
+	}
+	return args, nil
+}
+
+// capture returns the steps of a capture block, begun by s, alternatives
+// are captured by Either()
+func (p *dslParser) capture(s step, args []dslToken, alts []dslAlt, start dslToken) ([]posStep, error) {
+	if s.name == "BeginCapture" && len(args) != 0 || s.name == "BeginNamedCapture" && len(args) != 1 {
+		return nil, start.fail(ErrStepArgs)
+	}
+	for _, a := range args {
+		name, ok := a.value.(string)
+		if !ok {
+			return nil, a.fail(ErrStepArgs)
+		}
+		s.args = append(s.args, name)
+	}
+
+	steps := []posStep{{s, start}}
+	if len(alts) == 1 {
+		steps = append(steps, alts[0].steps...)
+	} else {
+		either, err := blockArgs(true, expressionType, alts)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, posStep{step{name: "Either", args: either}, start})
+	}
+	return append(steps, posStep{step{name: "EndCapture"}, start}), nil
+}
+
+// blockArgs returns the arguments of type t made by alternatives of a
+// block. Variadic methods take an argument per alternative, the others get
+// Or() steps.
+func blockArgs(variadic bool, t reflect.Type, alts []dslAlt) ([]interface{}, error) {
+	if !variadic {
+		if t == classType && len(alts) > 1 {
+			return nil, alts[1].at.fail(ErrUnexpected)
+		}
+		for _, alt := range alts[1:] {
+			body, err := nested(alt.steps)
+			if err != nil {
+				return nil, err
+			}
+			or := step{name: "Or", args: []interface{}{chain{flags: MULTILINE | GLOBAL, steps: body}}}
+			alts[0].steps = append(alts[0].steps, posStep{or, alt.at})
+		}
+		alts = alts[:1]
+	}
+
+	args := []interface{}{}
+	for _, alt := range alts {
+		steps, err := nested(alt.steps)
+		if err != nil {
+			return nil, err
+		}
+		switch t {
+		case closureType:
+			args = append(args, closure(steps))
+		case classType:
+			args = append(args, classChain(steps))
+		default:
+			args = append(args, chain{flags: MULTILINE | GLOBAL, steps: steps})
+		}
+	}
+	return args, nil
+}
+
+// nested returns steps with the steps of capture groups in their body, see
+// nest()
+func nested(steps []posStep) ([]step, error) {
+	depth := 0
+	flat := make([]step, len(steps))
+	for i, s := range steps {
+		switch {
+		case s.captures():
+			depth++
+		case s.name == "EndCapture" && depth == 0:
+			return nil, s.at.fail(ErrNoOpenGroup)
+		case s.name == "EndCapture":
+			depth--
+		}
+		flat[i] = s.step
+	}
+	result, _ := nest(flat)
+	return result, nil
+}
+
+// dslResolve returns the method of receiver written as words, capture
+// tells if it is "capture" or "capture as"
+func dslResolve(receiver reflect.Type, words []string) (name string, capture bool, m reflect.Method, ok bool) {
+	phrase := strings.Join(words, " ")
+	name = dslMethod(words)
+	capture = receiver == expressionType && (phrase == "capture" || phrase == "capture as")
+	if capture {
+		name = "BeginCapture"
+		if phrase == "capture as" {
+			name = "BeginNamedCapture"
+		}
+	}
+	m, ok = stepMethod(receiver, name)
+	return name, capture, m, ok
+}
+
+// dslBlock returns the type of the block of method m, nil if it has none
+func dslBlock(m reflect.Method, capture bool) reflect.Type {
+	if capture {
+		return closureType
+	}
+	if m.Type.NumIn() > 1 {
+		if t := paramType(m, 0); t == closureType || t == expressionType || t == classType {
+			return t
+		}
+	}
+	return nil
+}
+
+// dslMethod returns the name of the method written as words
+func dslMethod(words []string) string {
+	name := ""
+	for _, w := range words {
+		r, n := utf8.DecodeRuneInString(w)
+		name += string(unicode.ToUpper(r)) + w[n:]
+	}
+	return name
+}
+
+// dslValue returns the argument of type t written as token a
+func dslValue(t reflect.Type, a dslToken) (interface{}, bool) {
+	switch t.Kind() {
+	case reflect.String:
+		if r, ok := a.value.(rune); ok {
+			return string(r), true
+		}
+		s, ok := a.value.(string)
+		return s, ok
+	case reflect.Int32:
+		if s, ok := a.value.(string); ok && utf8.RuneCountInString(s) == 1 {
+			r, _ := utf8.DecodeRuneInString(s)
+			return r, true
+		}
+		r, ok := a.value.(rune)
+		return r, ok
+	case reflect.Int:
+		i, ok := a.value.(int)
+		return i, ok
+	case reflect.Bool:
+		return a.isWord("true"), a.isWord("true") || a.isWord("false")
+	case reflect.Interface:
+		// Range() bounds, strings or integers
+		switch v := a.value.(type) {
+		case string, int:
+			return v, true
+		case rune:
+			return string(v), true
+		}
+	}
+	return nil, false
+}
+
+// dslLines returns the lines of steps, Or() steps start alternatives
+func dslLines(steps []step) []string {
+	lines := []string{}
+	for _, s := range steps {
+		if c, ok := orChain(s); ok {
+			lines = append(lines, "or")
+			lines = append(lines, dslLines(append(flagSteps(c.flags), c.steps...))...)
+			continue
+		}
+		lines = append(lines, dslStepLines(s)...)
+	}
+	return lines
+}
+
+// orChain returns the expression added as an alternative by s, if it is an
+// Or() step
+func orChain(s step) (chain, bool) {
+	if s.name != "Or" || len(s.args) != 1 {
+		return chain{}, false
+	}
+	c, ok := s.args[0].(chain)
+	return c, ok
+}
+
+// dslStepLines returns the lines of step s, with its block
+func dslStepLines(s step) []string {
+	head := dslWords(s.name)
+	if s.name == "Find" {
+		// reads better, Then() is Find()
+		head = "then"
+	}
+	var blocks [][]step
+	values := []interface{}{}
+	if s.captures() {
+		head = "capture"
+		if s.name == "BeginNamedCapture" {
+			head += " as " + dslName(s.args[0].(string))
+		}
+		blocks = [][]step{s.body}
+	} else {
+		for _, arg := range s.args {
+			switch arg := arg.(type) {
+			case closure:
+				blocks = append(blocks, arg)
+			case chain:
+				blocks = append(blocks, append(flagSteps(arg.flags), arg.steps...))
+			case classChain:
+				blocks = append(blocks, arg)
+			default:
+				values = append(values, arg)
+			}
+		}
+	}
+
+	if len(values) > 0 && !(len(values) == 1 && values[0] == true) {
+		head += " " + dslValues(s.name, values)
+	}
+	if blocks == nil {
+		return []string{head}
+	}
+	if len(blocks) == 1 && len(blocks[0]) == 1 {
+		if line := dslLines(blocks[0]); len(line) == 1 {
+			return []string{head + ": " + line[0]}
+		}
+	}
+	lines := []string{head}
+	for i, b := range blocks {
+		if i > 0 {
+			lines = append(lines, "or")
+		}
+		for _, l := range dslLines(b) {
+			lines = append(lines, "\t"+l)
+		}
+	}
+	return append(lines, "end")
+}
+
+// dslWords returns the name of a method as lower case words
+func dslWords(name string) string {
+	words := ""
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			words += " "
+		}
+		words += string(unicode.ToLower(r))
+	}
+	return words
+}
+
+// dslName returns a capture group name, as a word if it is one
+func dslName(name string) string {
+	for i, r := range name {
+		if !isWordRune(r) || i == 0 && unicode.IsDigit(r) {
+			return goValue(name, 0)
+		}
+	}
+	return name
+}
+
+// dslValues returns the arguments of step "name"
+func dslValues(name string, values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = goValue(v, 0)
+	}
+	switch {
+	case name == "Between":
+		return strings.Join(parts, " and ")
+	case name == "Range" && len(parts)%2 == 0:
+		pairs := []string{}
+		for i := 0; i < len(parts); i += 2 {
+			pairs = append(pairs, parts[i]+" to "+parts[i+1])
+		}
+		return strings.Join(pairs, ", ")
+	}
+	return strings.Join(parts, ", ")
+}
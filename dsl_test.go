@@ -0,0 +1,179 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDSL(t *testing.T) {
+
+	tests := []struct {
+		src    string
+		expect *VerbalExpression
+	}{
+		{
+			`start of line / then "http" / maybe "s" / capture as host: word`,
+			New().StartOfLine().Then("http").Maybe("s").BeginNamedCapture("host").Word().EndCapture(),
+		},
+		{
+			"# an url\nstart of line\n\nthen \"http\"  # the scheme\nmaybe 's'\nthen \"://\"\n",
+			New().StartOfLine().Then("http").Maybe("s").Then("://"),
+		},
+		{
+			"search one line\nwith any case / stop at first true\nmatch all with dot false\nanything",
+			New().SearchOneLine(true).WithAnyCase(true).StopAtFirst(true).Anything(),
+		},
+		{
+			`digit / between 1 and 3 / lazy / letter / times 2 / range "a" to "f", 0 to 9 / multiple "x", 2, 5`,
+			New().Digit().Between(1, 3).Lazy().Letter().Times(2).Range("a", "f", 0, 9).Multiple("x", 2, 5),
+		},
+		{
+			"capture\n\tdigit\n\tcapture as \"n1\": letter\nend\none or more",
+			New().BeginCapture().Digit().BeginNamedCapture("n1").Letter().EndCapture().EndCapture().OneOrMore(),
+		},
+		{
+			"begin capture / then \"a\" / end capture / raw `\\d+`",
+			New().BeginCapture().Find("a").EndCapture().Raw(`\d+`),
+		},
+		{
+			"either\n\tthen \"cat\"\nor\n\tthen \"dog\"\nor\n\tsearch one line / start of line\nend",
+			New().Either(New().Find("cat"), New().Find("dog"), New().SearchOneLine(true).StartOfLine()),
+		},
+		{
+			"then \"a\"\nor\nthen \"b\"\nor\nmatch all with dot / anything",
+			New().Find("a").Or(New().Find("b")).Or(New().MatchAllWithDot(true).Anything()),
+		},
+		{
+			"group: then \"a\" / digit\none or more\ncase insensitive\n\tthen \"b\"\n\tcase sensitive: then \"c\"\nend",
+			New().Group(func(g *VerbalExpression) { g.Find("a").Digit() }).OneOrMore().CaseInsensitive(func(g *VerbalExpression) {
+				g.Find("b").CaseSensitive(func(g *VerbalExpression) { g.Find("c") })
+			}),
+		},
+		{
+			"class: letter / digit / chars \"_-\" / range 'α' to \"ω\" / not\nand\n\twith any case\n\tthen \"x\"\nend",
+			New().Class(NewClass().Letter().Digit().Chars("_-").Range('α', 'ω').Not()).And(New().WithAnyCase(true).Find("x")),
+		},
+		{
+			"capture word\ncapture as host digit / letter\ngroup then \"a\"\nclass letter",
+			New().BeginCapture().Word().EndCapture().BeginNamedCapture("host").Digit().Letter().EndCapture().
+				Group(func(g *VerbalExpression) { g.Find("a") }).Class(NewClass().Letter()),
+		},
+		{
+			"capture\n\tthen \"a\"\nor\n\tthen \"b\"\nend",
+			New().BeginCapture().Either(New().Find("a"), New().Find("b")).EndCapture(),
+		},
+		{"", New()},
+	}
+	for _, test := range tests {
+		v, err := ParseDSL(test.src)
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+			continue
+		}
+		assertStringEquals(v.String(), test.expect.String(), t)
+	}
+}
+
+func TestParseDSLErrors(t *testing.T) {
+
+	tests := []struct {
+		src          string
+		err          error
+		line, column int
+		message      string
+	}{
+		{"then \"a\"\nfrobnicate \"b\"", ErrUnknownStep, 2, 1, `verbalexpressions: line 2, column 1: frobnicate: unknown step`},
+		{"then \"a\" / then \"b", ErrBadLiteral, 1, 17, ""},
+		{"then 3", ErrStepArgs, 1, 6, ""},
+		{"times", ErrStepArgs, 1, 1, ""},
+		{"digit ; letter", ErrUnexpected, 1, 7, ""},
+		{"digit: letter", ErrUnexpected, 1, 6, ""},
+		{"then \"a\" \"b\"", ErrStepArgs, 1, 1, ""},
+		{"\ngroup\n\tdigit\n", ErrMissingEnd, 2, 1, ""},
+		{"digit\nend", ErrUnexpected, 2, 1, ""},
+		{"then \"a\" / times -1", ErrInvalidRepeat, 1, 12, `verbalexpressions: line 1, column 12: times: invalid min or max repetition`},
+		{"group\n\tthen \"a\"\n\tend capture\nend", ErrNoOpenGroup, 3, 2, ""},
+		{"capture as \"a b\": digit", ErrInvalidName, 1, 1, ""},
+		{"class\n\tletter\nor\n\tdigit\nend", ErrUnexpected, 3, 1, ""},
+		{"between 1 and", ErrUnexpected, 1, 14, ""},
+		{"digit letter", ErrUnknownStep, 1, 1, `verbalexpressions: line 1, column 1: digit letter: unknown step`},
+		{"group frobnicate", ErrUnknownStep, 1, 7, ""},
+		{"capture as x: digit\nand\n\tcapture as x: word\nend", ErrDuplicateName, 2, 1, ""},
+	}
+	for _, test := range tests {
+		_, err := ParseDSL(test.src)
+		var de *DSLError
+		if !errors.As(err, &de) || !errors.Is(err, test.err) {
+			t.Errorf("%q: error is %v, not %v", test.src, err, test.err)
+			continue
+		}
+		if de.Line != test.line || de.Column != test.column {
+			t.Errorf("%q: error at %d:%d, not %d:%d", test.src, de.Line, de.Column, test.line, test.column)
+		}
+		if test.message != "" {
+			assertStringEquals(err.Error(), test.message, t)
+		}
+	}
+}
+
+func TestDSL(t *testing.T) {
+
+	v := New().StartOfLine().Find("http").Maybe("s").Find("://").BeginNamedCapture("host").Word().EndCapture().
+		Either(New().Find("a"), New().Find("b").Digit()).Range("a", "z").Between(1, 3)
+	expect := `start of line
+then "http"
+maybe "s"
+then "://"
+capture as host: word
+either
+	then "a"
+or
+	then "b"
+	digit
+end
+range "a" to "z"
+between 1 and 3
+`
+	assertStringEquals(v.DSL(), expect, t)
+	assertStringEquals(New().DSL(), "", t)
+}
+
+func TestDSLRoundTrip(t *testing.T) {
+
+	tests := []*VerbalExpression{
+		New().StartOfLine().Find("http").Maybe("s").Find("://").Word().EndOfLine(),
+		New().SearchOneLine(true).WithAnyCase(true).MatchAllWithDot(true).Ungreedy(true).StopAtFirst(true).Anything(),
+		New().AnythingBut("ab").SomethingBut("c").Lazy().AnythingLazy().SomethingLazy().Something(),
+		New().Not("abc").LineBreak().Tab().WholeWord("cat").Multiple("x", 2, 5).Multiple("y", 3).Multiple("z"),
+		New().Find("a").Not("").Find("b"),
+		New().Any("xyz").Range("a", "f", 0, 9).Digit().Whitespace().Letter().HexDigit().Punctuation(),
+		New().Class(NewClass().Letter().Digit().Chars("_-").Range('α', 'ω').Not()),
+		New().UnicodeLetter().UnicodeScript("Han").UnicodeCategory("Lu").UnicodeWord(),
+		New().StartOfInput().WordBoundary().NotWordBoundary().EndOfInput(),
+		New().BeginCapture().Find("a").BeginNamedCapture("b").Digit().EndCapture().EndCapture().Optional(),
+		New().Find("a").OneOrMore().Digit().ZeroOrMore().Letter().Times(2).Word().AtLeast(2).Find("b").Between(1, 3).Lazy(),
+		New().Group(func(g *VerbalExpression) { g.Find("a").Digit() }).OneOrMore(),
+		New().CaseInsensitive(func(g *VerbalExpression) {
+			g.Find("a").CaseSensitive(func(g *VerbalExpression) { g.Find("b") }).DotMatchesNewline(func(g *VerbalExpression) {
+				g.Anything()
+			})
+		}).FindIgnoreCase("c"),
+		New().Either(New().Find("a"), New().SearchOneLine(true).StartOfLine(), New().Find("b").Or(New().Find("c"))).OneOf("d", "e"),
+		New().Find("a").And(New().WithAnyCase(true).Find("b")).Or(New().MatchAllWithDot(true).Anything()),
+		New().Raw(`(\d)+`).Raw(`(?i)x`).Optional().Find("\"quoted\"\n"),
+		New().BeginNamedCapture("_1").Find("a").EndCapture(),
+	}
+	for _, v := range tests {
+		src := v.DSL()
+		r, err := ParseDSL(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		assertStringEquals(r.String(), v.String(), t)
+		assertStringEquals(r.DSL(), src, t)
+	}
+}
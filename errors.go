@@ -0,0 +1,109 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors reported by builder methods, wrapped in a StepError
+var (
+	ErrOddRangeArgs        = errors.New("not even args number")
+	ErrTooManyMultiples    = errors.New("you can only give 1 or 2 multipliers, min and max as int")
+	ErrUnsupportedType     = errors.New("unsupported argument type")
+	ErrRangeBound          = errors.New("range bounds must be single characters")
+	ErrInvalidRepeat       = errors.New("invalid min or max repetition")
+	ErrNoOpenGroup         = errors.New("no group to end")
+	ErrInvalidName         = errors.New("invalid capture group name")
+	ErrDuplicateName       = errors.New("duplicate capture group name")
+	ErrNothingToRepeat     = errors.New("no previous step to repeat")
+	ErrNotRepeated         = errors.New("previous step is not a repetition")
+	ErrNoAlternative       = errors.New("at least one alternative is needed")
+	ErrInvalidRange        = errors.New("range start is after range end")
+	ErrNegatedUnion        = errors.New("negated class can't be part of a union")
+	ErrEmptyClass          = errors.New("empty character class")
+	ErrUnknownUnicodeClass = errors.New("unknown unicode script or category")
+	ErrUnknownStep         = errors.New("unknown step")
+	ErrStepArgs            = errors.New("invalid step arguments")
+	ErrUnknownFlag         = errors.New("unknown flag")
+	ErrUnexpected          = errors.New("unexpected text")
+	ErrBadLiteral          = errors.New("invalid string, rune or integer")
+	ErrMissingEnd          = errors.New("block has no end")
+)
+
+// StepError is the error recorded when a builder method receives arguments
+// it can't turn into a regular expression. Step is the method name and Args
+// the arguments it was called with.
+type StepError struct {
+	Step string
+	Args []interface{}
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	args := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		args[i] = formatArg(a)
+	}
+	return fmt.Sprintf("verbalexpressions: %s(%s): %v", e.Step, strings.Join(args, ", "), e.Err)
+}
+
+// formatArg returns a Go like representation of a builder argument
+func formatArg(a interface{}) string {
+	if _, ok := a.(*VerbalExpression); ok {
+		return "*VerbalExpression"
+	}
+	return fmt.Sprintf("%#v", a)
+}
+
+// Unwrap returns the underlying error, so errors.Is works with StepError
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// DSLError is the error of ParseDSL() for an invalid source, at Line and
+// Column, counted from 1. Text is the faulty text, if any.
+type DSLError struct {
+	Line, Column int
+	Text         string
+	Err          error
+}
+
+func (e *DSLError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("verbalexpressions: line %d, column %d: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("verbalexpressions: line %d, column %d: %s: %v", e.Line, e.Column, e.Text, e.Err)
+}
+
+// Unwrap returns the underlying error, so errors.Is works with DSLError
+func (e *DSLError) Unwrap() error {
+	return e.Err
+}
+
+// fail records the first error encountered while building the expression.
+// Next errors are ignored, the first one is the one to fix.
+func (v *VerbalExpression) fail(step string, err error, args ...interface{}) *VerbalExpression {
+	if v.err != nil {
+		return v
+	}
+	v = v.fork()
+	if se, ok := err.(*StepError); ok {
+		// error from a sub expression, report the failing step as is
+		v.err = se
+	} else {
+		v.err = &StepError{Step: step, Args: args, Err: err}
+	}
+	v.invalidate()
+	return v
+}
+
+// Err returns the first error that happened while building the expression,
+// or nil. Builder methods never panic: check Err() or use Compile() to know
+// if the expression is valid.
+func (v *VerbalExpression) Err() error {
+	return v.err
+}
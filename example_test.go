@@ -82,3 +82,50 @@ func ExampleVerbalExpression_AnythingBut() {
 	fmt.Println(v)
 	//Output: [Th s  s a s mpl  t st]
 }
+
+func ExampleVerbalExpression_CaptureMap() {
+
+	s := "Contact: john@example.com"
+	v := verbalexpressions.New().
+		BeginNamedCapture("user").Word().EndCapture().
+		Find("@").
+		BeginNamedCapture("domain").SomethingBut(" ").EndCapture()
+
+	m := v.CaptureMap(s)
+	fmt.Println(m["user"], m["domain"])
+	//Output: john example.com
+}
+
+func ExampleVerbalExpression_Either() {
+
+	s := "http://a.com ftp://b.com mailto:c@d.com"
+	v := verbalexpressions.New().
+		Either(
+			verbalexpressions.New().Find("http").Maybe("s"),
+			verbalexpressions.New().Find("ftp"),
+		).
+		Find("://").
+		SomethingBut(" ")
+
+	fmt.Println(v.Regex().FindAllString(s, -1))
+	//Output: [http://a.com ftp://b.com]
+}
+
+func ExampleFromRegex() {
+
+	v, err := verbalexpressions.FromRegex(`(?i)^id-\d+$`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(v.GoSource())
+	fmt.Println(v.Test("ID-42"))
+	//Output: verbalexpressions.New().
+	//	SearchOneLine(true).
+	//	StartOfLine().
+	//	FindIgnoreCase("id-").
+	//	Digit().
+	//	OneOrMore().
+	//	EndOfLine()
+	// true
+}
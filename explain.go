@@ -0,0 +1,264 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* human readable description of the expression tree */
+
+// Explain returns an english description of the expression, one step per
+// line, nested steps are indented. For example:
+//
+//	verbalexpressions.New().Anything().BeginCapture().Find("bar").Word().EndCapture().Explain()
+//
+// returns:
+//
+//	options: multiline
+//	any character except a line break, zero or more times
+//	capture group 1:
+//	  the text "bar"
+//	  a word character, one or more times
+func (v *VerbalExpression) Explain() string {
+	e := &explainer{flags: v.flags & regexFlags}
+	if options := flagNames(e.flags, 0); options != "" {
+		e.line("options: " + options)
+	}
+	e.node(v.body())
+	if v.err != nil {
+		e.line("error: " + v.err.Error())
+	}
+	return strings.Join(e.lines, "\n")
+}
+
+// explainer writes descriptions of nodes
+type explainer struct {
+	lines []string
+	depth int
+	flags Flag // flags active at the current position, as for renderer
+	caps  int  // capture groups found so far, to number them
+}
+
+// line adds a description at the current depth
+func (e *explainer) line(s string) {
+	e.lines = append(e.lines, strings.Repeat("  ", e.depth)+s)
+}
+
+// nested adds a header line, then descriptions of n below it
+func (e *explainer) nested(header string, n node) {
+	e.line(header + ":")
+	e.depth++
+	e.node(n)
+	e.depth--
+}
+
+// node adds the description of n
+func (e *explainer) node(n node) {
+	if s, ok := e.phrase(n); ok {
+		e.line(s)
+		return
+	}
+
+	switch n := n.(type) {
+	case *sequence:
+		for _, child := range n.nodes {
+			e.node(child)
+		}
+	case *alternation:
+		for i, alt := range n.alts {
+			if i == 0 {
+				e.nested("either", alt)
+			} else {
+				e.nested("or", alt)
+			}
+		}
+	case *group:
+		e.group(n)
+	case *quantifier:
+		e.nested(repeatPhrase(n, e.flags), n.child)
+	case *raw:
+		e.line(rawPhrase(n, e.caps))
+		e.caps += n.caps
+	}
+}
+
+// group adds the description of a group, and of the flags it sets
+func (e *explainer) group(g *group) {
+	saved := e.flags
+	on, off := g.on&^e.flags, g.off&e.flags
+	e.flags = (e.flags | on) &^ off
+
+	header := flagNames(on, off)
+	if g.capture {
+		e.caps++
+		capture := "capture group " + strconv.Itoa(e.caps)
+		if g.name != "" {
+			capture += " " + strconv.Quote(g.name)
+		}
+		if header != "" {
+			header = capture + ", " + header
+		} else {
+			header = capture
+		}
+	}
+	if header == "" {
+		e.node(g.body)
+	} else {
+		e.nested(header, g.body)
+	}
+	e.flags = saved
+}
+
+// phrase returns a one line description of n, if n is simple enough
+func (e *explainer) phrase(n node) (string, bool) {
+	switch n := n.(type) {
+	case *literal:
+		return "the text " + strconv.Quote(n.text), true
+	case *class:
+		return classPhrase(n), true
+	case *anyChar:
+		if e.flags&DOTALL != 0 {
+			return "any character", true
+		}
+		return "any character except a line break", true
+	case *anchor:
+		return anchorPhrases[n.kind], true
+	case *raw:
+		if n.caps == 0 {
+			return rawPhrase(n, e.caps), true
+		}
+	case *sequence:
+		if len(n.nodes) == 1 {
+			return e.phrase(n.nodes[0])
+		}
+	case *group:
+		if !n.capture && n.on&^e.flags == 0 && n.off&e.flags == 0 {
+			return e.phrase(n.body)
+		}
+	case *quantifier:
+		if s, ok := e.phrase(n.child); ok {
+			return s + ", " + repeatPhrase(n, e.flags), true
+		}
+	}
+	return "", false
+}
+
+// repeatPhrase describes how many times a quantifier repeats its child,
+// flags are the flags active at q
+func repeatPhrase(q *quantifier, flags Flag) string {
+	var s string
+	switch {
+	case q.min == 0 && q.max == 1:
+		s = "optional"
+	case q.min == 0 && q.max == -1:
+		s = "zero or more times"
+	case q.min == 1 && q.max == -1:
+		s = "one or more times"
+	case q.max == -1:
+		s = fmt.Sprintf("%d or more times", q.min)
+	case q.min == q.max:
+		s = fmt.Sprintf("exactly %d times", q.min)
+	default:
+		s = fmt.Sprintf("from %d to %d times", q.min, q.max)
+	}
+	if q.lazy || flags&UNGREEDY != 0 {
+		s += ", as few as possible"
+	}
+	return s
+}
+
+// rawPhrase describes a fragment of regular expression, caps is the number
+// of capture groups before it
+func rawPhrase(r *raw, caps int) string {
+	s := "the regular expression " + strconv.Quote(r.text)
+	switch r.caps {
+	case 0:
+	case 1:
+		s += fmt.Sprintf(" (capture group %d)", caps+1)
+	default:
+		s += fmt.Sprintf(" (capture groups %d to %d)", caps+1, caps+r.caps)
+	}
+	return s
+}
+
+var anchorPhrases = map[anchorKind]string{
+	startOfLine:     "the start of the line",
+	endOfLine:       "the end of the line",
+	startOfInput:    "the start of the input",
+	endOfInput:      "the end of the input",
+	wordBoundary:    "a word boundary",
+	notWordBoundary: "not a word boundary",
+}
+
+// classNames are descriptions of predefined classes
+var classNames = map[string]string{
+	`\w`:        "word character",
+	`\d`:        "digit",
+	`\s`:        "whitespace character",
+	`\p{L}`:     "unicode letter",
+	`\p{M}`:     "unicode mark",
+	`\p{Nd}`:    "unicode digit",
+	`\p{Pc}`:    "unicode connector",
+	`[:punct:]`: "punctuation character",
+}
+
+// classPhrase describes a character class
+func classPhrase(c *class) string {
+	if !c.negate && len(c.items) == 1 && c.items[0].name != "" {
+		return "a " + className(c.items[0].name)
+	}
+
+	parts := []string{}
+	for _, item := range c.items {
+		switch {
+		case item.name != "":
+			parts = append(parts, className(item.name))
+		case item.lo == item.hi:
+			parts = append(parts, strconv.QuoteRune(item.lo))
+		default:
+			parts = append(parts, strconv.QuoteRune(item.lo)+" to "+strconv.QuoteRune(item.hi))
+		}
+	}
+	if c.negate {
+		return "a character except " + strings.Join(parts, ", ")
+	}
+	return "one character of " + strings.Join(parts, ", ")
+}
+
+// className describes a predefined class, unicode scripts and categories
+// are described by their name
+func className(name string) string {
+	if s, ok := classNames[name]; ok {
+		return s
+	}
+	if strings.HasPrefix(name, `\p{`) {
+		return "character of " + strings.TrimSuffix(strings.TrimPrefix(name, `\p{`), "}")
+	}
+	return name
+}
+
+// flagNames describes flags set and unset
+func flagNames(on, off Flag) string {
+	names := []string{}
+	for _, f := range []struct {
+		flag    Flag
+		on, off string
+	}{
+		{MULTILINE, "multiline", "single line"},
+		{IGNORE_CASE, "ignoring case", "case sensitive"},
+		{DOTALL, "dot matches line breaks", "dot doesn't match line breaks"},
+		{UNGREEDY, "ungreedy", "greedy"},
+	} {
+		if on&f.flag != 0 {
+			names = append(names, f.on)
+		}
+		if off&f.flag != 0 {
+			names = append(names, f.off)
+		}
+	}
+	return strings.Join(names, ", ")
+}
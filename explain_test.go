@@ -0,0 +1,71 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import "testing"
+
+func TestExplain(t *testing.T) {
+
+	v := New().Anything().BeginCapture().Find("bar").Word().EndCapture()
+	assertStringEquals(v.Explain(), `options: multiline
+any character except a line break, zero or more times
+capture group 1:
+  the text "bar"
+  a word character, one or more times`, t)
+
+	v = New().SearchOneLine(true).StartOfLine().
+		Either(New().Find("http").Maybe("s"), New().Find("ftp").WithAnyCase(true)).
+		Find("://").
+		BeginNamedCapture("host").AnythingBut("/ ").EndCapture().
+		Group(func(g *VerbalExpression) {
+			g.Find("/").Class(NewClass().Letter().Digit())
+		}).ZeroOrMore().Lazy().
+		EndOfInput()
+	assertStringEquals(v.Explain(), `the start of the line
+either:
+  the text "http"
+  the text "s", optional
+or:
+  ignoring case:
+    the text "ftp"
+the text "://"
+capture group 1 "host":
+  a character except '/', ' ', zero or more times
+zero or more times, as few as possible:
+  the text "/"
+  one character of 'a' to 'z', 'A' to 'Z', digit
+the end of the input`, t)
+}
+
+func TestExplainUngreedy(t *testing.T) {
+
+	v := New().Ungreedy(true).Find("<").AnythingLazy().Find(">").Digit().OneOrMore().
+		Group(func(g *VerbalExpression) {
+			g.Ungreedy(false).Word().Lazy().Digit().OneOrMore()
+		})
+	assertStringEquals(v.String(), `(?mU)<.*>\d+(?-U:\w+?\d+)`, t)
+	assertStringEquals(v.Explain(), `options: multiline, ungreedy
+the text "<"
+any character except a line break, zero or more times, as few as possible
+the text ">"
+a digit, one or more times, as few as possible
+greedy:
+  a word character, one or more times, as few as possible
+  a digit, one or more times`, t)
+}
+
+func TestExplainRawAndErrors(t *testing.T) {
+
+	v := New().SearchOneLine(true).BeginCapture().Digit().EndCapture().Raw(`(\d)-(\d)`).Capture(func(c *VerbalExpression) {
+		c.UnicodeScript("Han").FindIgnoreCase("x")
+	}).Range("a")
+	assertStringEquals(v.Explain(), `capture group 1:
+  a digit
+the regular expression "(\\d)-(\\d)" (capture groups 2 to 3)
+capture group 4:
+  a character of Han
+  ignoring case:
+    the text "x"
+error: verbalexpressions: Range("a"): not even args number`, t)
+}
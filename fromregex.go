@@ -0,0 +1,346 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+/* regular expressions written by hand, turned into builder steps */
+
+// FromRegex parses a regular expression in RE2 syntax, as regexp.Compile
+// does, and returns a VerbalExpression matching the same strings with the
+// same capture groups. Names of capture groups must be unique, as for
+// BeginNamedCapture(), though regexp accepts "(?P<x>a)|(?P<x>b)": such a
+// pattern returns an ErrDuplicateName error. With GoSource(), it helps
+// moving existing patterns to the verbal style:
+//
+//	v, err := verbalexpressions.FromRegex(`^https?://(\w+)`)
+//	if err != nil {
+//		...
+//	}
+//	fmt.Println(v.GoSource())
+//
+// prints:
+//
+//	verbalexpressions.New().
+//		SearchOneLine(true).
+//		StartOfLine().
+//		Find("http").
+//		Maybe("s").
+//		Find("://").
+//		BeginCapture().
+//		Word().
+//		EndCapture()
+//
+// Flags of the pattern, like "(?i)", are set on the steps they apply to. The
+// parts that no step matches, like a single ".", are added with Raw().
+func FromRegex(pattern string) (*VerbalExpression, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &converter{}
+	var lines, texts, dots, dotsNL bool
+	var dup string
+	names := map[string]bool{}
+	scan(re, func(re *syntax.Regexp) {
+		switch re.Op {
+		case syntax.OpCapture:
+			if re.Name != "" && names[re.Name] && dup == "" {
+				dup = re.Name
+			}
+			names[re.Name] = true
+		case syntax.OpBeginLine, syntax.OpEndLine:
+			lines = true
+		case syntax.OpBeginText, syntax.OpEndText:
+			texts = true
+		case syntax.OpAnyCharNotNL:
+			dots = true
+		case syntax.OpAnyChar:
+			dotsNL = true
+		}
+	})
+	if dup != "" {
+		return nil, &StepError{Step: "FromRegex", Args: []interface{}{pattern}, Err: fmt.Errorf("%w %q", ErrDuplicateName, dup)}
+	}
+	// "^" and "$" are written as is if they are not used in multiline mode
+	c.oneLine = texts && !lines
+	c.dotAll = dotsNL && !dots
+
+	tree := New()
+	if c.oneLine {
+		tree.flags &^= MULTILINE
+	}
+	if c.dotAll {
+		tree.flags |= DOTALL
+	}
+	if re.Op == syntax.OpAlternate {
+		// first alternative is the expression, others are chained with Or()
+		tree.root.add(c.node(re.Sub[0]))
+		for _, sub := range re.Sub[1:] {
+			tree.alternatives = append(tree.alternatives, &group{body: c.node(sub)})
+		}
+	} else {
+		tree.root.add(c.node(re))
+	}
+
+	v := tree.chain().build()
+	return v, v.Err()
+}
+
+// scan calls f for re and each of its sub expressions
+func scan(re *syntax.Regexp, f func(*syntax.Regexp)) {
+	f(re)
+	for _, sub := range re.Sub {
+		scan(sub, f)
+	}
+}
+
+// converter turns a parsed regular expression into an expression tree
+type converter struct {
+	oneLine bool // multiline mode is not set, "^" and "$" match the whole text
+	dotAll  bool // DOTALL is set, "." matches "\n"
+}
+
+// node returns the tree matching re
+func (c *converter) node(re *syntax.Regexp) node {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return noMatch()
+	case syntax.OpLiteral:
+		return c.literal(re)
+	case syntax.OpCharClass:
+		return c.class(re.Rune)
+	case syntax.OpAnyCharNotNL:
+		return &anyChar{}
+	case syntax.OpAnyChar:
+		return c.dot(&anyChar{})
+	case syntax.OpBeginLine:
+		return &anchor{startOfLine}
+	case syntax.OpEndLine:
+		return &anchor{endOfLine}
+	case syntax.OpBeginText:
+		if c.oneLine {
+			return &anchor{startOfLine}
+		}
+		return &anchor{startOfInput}
+	case syntax.OpEndText:
+		if c.oneLine && re.Flags&syntax.WasDollar != 0 {
+			return &anchor{endOfLine}
+		}
+		return &anchor{endOfInput}
+	case syntax.OpWordBoundary:
+		return &anchor{wordBoundary}
+	case syntax.OpNoWordBoundary:
+		return &anchor{notWordBoundary}
+	case syntax.OpCapture:
+		body := &sequence{}
+		body.add(c.node(re.Sub[0]))
+		return &group{capture: true, name: re.Name, body: body}
+	case syntax.OpStar:
+		return c.repeat(re, 0, -1)
+	case syntax.OpPlus:
+		return c.repeat(re, 1, -1)
+	case syntax.OpQuest:
+		return c.repeat(re, 0, 1)
+	case syntax.OpRepeat:
+		return c.repeat(re, re.Min, re.Max)
+	case syntax.OpConcat:
+		s := &sequence{}
+		for _, sub := range re.Sub {
+			s.add(c.node(sub))
+		}
+		return s
+	case syntax.OpAlternate:
+		return c.alternate(re.Sub)
+	}
+	// OpEmptyMatch
+	return &sequence{}
+}
+
+// noMatch returns a class without characters, it matches nothing
+func noMatch() node {
+	return &raw{text: `[^\x00-\x{10FFFF}]`, atomic: true}
+}
+
+// dot returns n, in a group where "." matches "\n" if it is not the case
+// for the whole expression
+func (c *converter) dot(n node) node {
+	if c.dotAll {
+		return n
+	}
+	return &group{on: DOTALL, body: n}
+}
+
+// literal returns the text of re, in a case insensitive group if re has
+// the FoldCase flag
+func (c *converter) literal(re *syntax.Regexp) node {
+	text := string(re.Rune)
+	if re.Flags&syntax.FoldCase == 0 || !folds(re.Rune) {
+		return &literal{text}
+	}
+	// the parser keeps the smallest rune of each case, upper case for ASCII
+	return &group{on: IGNORE_CASE, body: &literal{strings.ToLower(text)}}
+}
+
+// folds tells if a rune of runes has other cases, as "ß" and "ẞ" whose
+// ToLower and ToUpper don't change
+func folds(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.SimpleFold(r) != r {
+			return true
+		}
+	}
+	return false
+}
+
+// repeat returns the quantifier of re
+func (c *converter) repeat(re *syntax.Regexp, min, max int) node {
+	lazy := re.Flags&syntax.NonGreedy != 0
+	if re.Sub[0].Op == syntax.OpAnyChar {
+		// Anything() or Something() where "." matches "\n"
+		return c.dot(&quantifier{child: &anyChar{}, min: min, max: max, lazy: lazy})
+	}
+	return &quantifier{child: c.node(re.Sub[0]), min: min, max: max, lazy: lazy}
+}
+
+// alternate returns an alternation of subs. The parser writes "a?" as "a|"
+// in some cases, it is turned back to a quantifier.
+func (c *converter) alternate(subs []*syntax.Regexp) node {
+	if len(subs) == 2 {
+		switch {
+		case subs[1].Op == syntax.OpEmptyMatch:
+			return &quantifier{child: c.node(subs[0]), min: 0, max: 1}
+		case subs[0].Op == syntax.OpEmptyMatch:
+			return &quantifier{child: c.node(subs[1]), min: 0, max: 1, lazy: true}
+		}
+	}
+
+	a := &alternation{}
+	for _, sub := range subs {
+		a.alts = append(a.alts, c.node(sub))
+	}
+	return a
+}
+
+// parsedClasses are ranges of classes with a step, as sorted by the parser
+var parsedClasses = []struct {
+	runes []rune
+	items []classItem
+}{
+	{[]rune{'0', '9'}, digitItems},
+	{[]rune{'\t', '\n', '\f', '\r', ' ', ' '}, whitespaceItems},
+	{[]rune{'A', 'Z', 'a', 'z'}, letterItems},
+	{[]rune{'0', '9', 'A', 'F', 'a', 'f'}, hexDigitItems},
+	{[]rune{'!', '/', ':', '@', '[', '`', '{', '~'}, punctuationItems},
+	{[]rune{'0', '9', 'A', 'Z', '_', '_', 'a', 'z'}, wordItems},
+}
+
+// class returns the class made of runes, pairs of range bounds. Negated
+// classes are parsed as the ranges of characters they don't contain, they
+// are negated again to be readable.
+func (c *converter) class(runes []rune) node {
+	if len(runes) == 0 {
+		return noMatch()
+	}
+	cl := &class{}
+	if len(runes) > 2 && runes[0] == 0 && runes[len(runes)-1] == unicode.MaxRune {
+		cl.negate = true
+		runes = complement(runes)
+	}
+
+	for _, p := range parsedClasses {
+		if sameRunes(runes, p.runes) {
+			cl.items = p.items
+			return cl
+		}
+	}
+	if name, ok := unicodeName(runes); ok {
+		cl.items = []classItem{{name: `\p{` + name + `}`}}
+		return cl
+	}
+	for i := 0; i < len(runes); i += 2 {
+		cl.items = append(cl.items, classItem{lo: runes[i], hi: runes[i+1]})
+	}
+	return cl
+}
+
+// complement returns the ranges between ranges of runes
+func complement(runes []rune) []rune {
+	c := []rune{}
+	for i := 1; i+1 < len(runes); i += 2 {
+		c = append(c, runes[i]+1, runes[i+1]-1)
+	}
+	return c
+}
+
+// sameRunes tells if a and b are the same ranges
+func sameRunes(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// unicodeName returns the name of the category or script made of ranges
+// runes, only non ASCII classes are checked
+func unicodeName(runes []rune) (string, bool) {
+	if len(runes) == 0 || runes[len(runes)-1] < unicode.MaxASCII {
+		return "", false
+	}
+	for _, tables := range []map[string]*unicode.RangeTable{unicode.Categories, unicode.Scripts} {
+		names := make([]string, 0, len(tables))
+		for name := range tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sameRunes(runes, tableRunes(tables[name])) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// tableRunes returns ranges of a unicode table, merged as the parser does
+func tableRunes(t *unicode.RangeTable) []rune {
+	runes := []rune{}
+	add := func(lo, hi rune) {
+		if n := len(runes); n > 0 && lo <= runes[n-1]+1 {
+			if hi > runes[n-1] {
+				runes[n-1] = hi
+			}
+			return
+		}
+		runes = append(runes, lo, hi)
+	}
+	each := func(lo, hi, stride rune) {
+		if stride == 1 {
+			add(lo, hi)
+			return
+		}
+		for r := lo; r <= hi; r += stride {
+			add(r, r)
+		}
+	}
+	for _, r := range t.R16 {
+		each(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range t.R32 {
+		each(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return runes
+}
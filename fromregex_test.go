@@ -0,0 +1,115 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"testing"
+)
+
+func TestFromRegex(t *testing.T) {
+
+	v, err := FromRegex(`^https?://(\w+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStringEquals(v.String(), `^https?://(\w+)`, t)
+	assertStringEquals(v.GoSource(), `verbalexpressions.New().
+	SearchOneLine(true).
+	StartOfLine().
+	Find("http").
+	Maybe("s").
+	Find("://").
+	BeginCapture().
+	Word().
+	EndCapture()`, t)
+
+	tests := []struct {
+		pattern, expect string
+	}{
+		{`(?m)^\s*#.*$`, `(?m)^\s*#.*$`},
+		{`(?s)a.*`, `(?ms)a.*`},
+		{`(?i)hello`, `(?m)(?i:hello)`},
+		{`a.b(?s:.)+?`, `(?m)a.b(?s:.+?)`},
+		{`[^abc]+\pL\p{Greek}[^\PN]`, `(?m)[^a-c]+\p{L}\p{Greek}\p{N}`},
+		{`[[:punct:]][[:xdigit:]][[:alpha:]]\t+`, `(?m)[[:punct:]][0-9A-Fa-f][a-zA-Z]\t+`},
+		{`x(?:foo|ba.)*y`, `(?m)x(?:foo|ba.)*y`},
+		{`\bcat\b|\Bdog`, `(?m)\bcat\b|\Bdog`},
+		{`\Aa{3}b{2,}c{1,5}?\z`, `^a{3}b{2,}c{1,5}?\z`},
+		{`(?P<year>\d{4})-(\d\d)`, `(?m)(?P<year>\d{4})-(\d\d)`},
+	}
+	for _, test := range tests {
+		v, err := FromRegex(test.pattern)
+		if err != nil {
+			t.Errorf("%s: %v", test.pattern, err)
+			continue
+		}
+		assertStringEquals(v.String(), test.expect, t)
+	}
+}
+
+func TestFromRegexMatchesTheSame(t *testing.T) {
+
+	input := "Hello World\nhttps://example.com/a?b=c 2013-07-01\r\n\tfoo_bar-baz@mail.org\n" +
+		"ΑΒΓ δεζ 東京 x=1; y = 22;\nCAT concatenate dog hotdog ab abab aaa bbb\n" +
+		"STRAẞE straße Kelvin kelvin"
+	patterns := []string{
+		`\w+`,
+		`(?i)hello\s+(world)`,
+		`(?m)^(\w+)\s`,
+		`^Hello|org$`,
+		`(?s)World.*?https`,
+		`https?://([^/\s]+)(/\S*)?`,
+		`(?P<y>\d{4})-(?P<m>\d{2})-(?P<d>\d{2})`,
+		`[\w.-]+@[\w-]+\.[a-z]{2,}`,
+		`\p{Greek}+|\p{Han}+`,
+		`[^\x00-\x7F]+`,
+		`(\w)=\s*(\d+);?`,
+		`\bcat\b|(?i:\bcat\b)|dog\b`,
+		`(ab)+|a{2,}?|b{1,2}`,
+		`.\n.`,
+		`(?U)a+b*`,
+		`x*`,
+		`[^\n]+$`,
+		`(?:)`,
+		`[^\x00-\x{10FFFF}]`,
+		`(?i)ß`,
+		`(?i)k`,
+	}
+	for _, p := range patterns {
+		v, err := FromRegex(p)
+		if err != nil {
+			t.Errorf("%s: %v", p, err)
+			continue
+		}
+		expect := regexp.MustCompile(p)
+		got := v.Regex()
+		if !reflect.DeepEqual(got.SubexpNames(), expect.SubexpNames()) {
+			t.Errorf("%s: groups of %s are %q, not %q", p, got, got.SubexpNames(), expect.SubexpNames())
+		}
+		if !reflect.DeepEqual(got.FindAllStringSubmatchIndex(input, -1), expect.FindAllStringSubmatchIndex(input, -1)) {
+			t.Errorf("%s: %s doesn't match the same", p, got)
+		}
+	}
+}
+
+func TestFromRegexErrors(t *testing.T) {
+
+	_, err := FromRegex(`a(b`)
+	var serr *syntax.Error
+	if !errors.As(err, &serr) || serr.Code != syntax.ErrMissingParen {
+		t.Errorf("%v is not a missing paren error", err)
+	}
+
+	// regexp accepts it
+	_, err = FromRegex(`(?P<x>a)|(?P<y>b)(?P<x>c)`)
+	if !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName", err)
+	} else {
+		assertStringEquals(err.Error(), `verbalexpressions: FromRegex("(?P<x>a)|(?P<y>b)(?P<x>c)"): duplicate capture group name "x"`, t)
+	}
+}
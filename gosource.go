@@ -0,0 +1,96 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* Go code of the builder calls making an expression */
+
+// maxLine is the length of a chain of calls written on one line
+const maxLine = 72
+
+// GoSource returns the Go code building the expression with the fluent
+// methods, to paste in a program. For example:
+//
+//	verbalexpressions.New().StartOfLine().Find("http").Maybe("s").Find("://").Word().GoSource()
+//
+// returns:
+//
+//	verbalexpressions.New().
+//		StartOfLine().
+//		Find("http").
+//		Maybe("s").
+//		Find("://").
+//		Word()
+//
+// The code may differ from the calls that built the expression, but it
+// makes the same regular expression. Steps that failed are not part of it,
+// see Err().
+func (v *VerbalExpression) GoSource() string {
+	c := v.chain()
+	return goChain("verbalexpressions.New()", append(flagSteps(c.flags), c.steps...), 0)
+}
+
+// goChain returns head followed by calls of steps, one per line if they
+// don't fit on one. Depth is the indentation of the first line.
+func goChain(head string, steps []step, depth int) string {
+	calls := []string{}
+	for _, s := range flatten(steps) {
+		calls = append(calls, goCall(s, depth+1))
+	}
+	line := strings.Join(append([]string{head}, calls...), ".")
+	if len(calls) < 2 || len(line) <= maxLine && !strings.Contains(line, "\n") {
+		return line
+	}
+	indent := "\n" + strings.Repeat("\t", depth+1)
+	return head + "." + indent + strings.Join(calls, "."+indent)
+}
+
+// goCall returns the call of s, arguments are written one per line if they
+// don't fit on one
+func goCall(s step, depth int) string {
+	if len(s.args) == 1 {
+		if c, ok := s.args[0].(closure); ok {
+			f := "func(g *verbalexpressions.VerbalExpression) {"
+			if len(c) == 0 {
+				return s.name + "(" + f + "})"
+			}
+			indent := strings.Repeat("\t", depth)
+			return s.name + "(" + f + "\n" + indent + "\t" + goChain("g", c, depth+1) + "\n" + indent + "})"
+		}
+	}
+
+	args := make([]string, len(s.args))
+	for i, arg := range s.args {
+		args[i] = goValue(arg, depth+1)
+	}
+	call := s.name + "(" + strings.Join(args, ", ") + ")"
+	if len(call) <= maxLine && !strings.Contains(call, "\n") {
+		return call
+	}
+	indent := "\n" + strings.Repeat("\t", depth+1)
+	return s.name + "(" + indent + strings.Join(args, ","+indent) + ",\n" + strings.Repeat("\t", depth) + ")"
+}
+
+// goValue returns the Go code of an argument
+func goValue(arg interface{}, depth int) string {
+	switch arg := arg.(type) {
+	case string:
+		if strings.Contains(arg, `\`) && strconv.CanBackquote(arg) {
+			return "`" + arg + "`"
+		}
+		return strconv.Quote(arg)
+	case rune:
+		return strconv.QuoteRune(arg)
+	case chain:
+		return goChain("verbalexpressions.New()", append(flagSteps(arg.flags), arg.steps...), depth)
+	case classChain:
+		return goChain("verbalexpressions.NewClass()", arg, depth)
+	}
+	return fmt.Sprint(arg)
+}
@@ -0,0 +1,63 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"go/parser"
+	"testing"
+)
+
+func TestGoSource(t *testing.T) {
+
+	v := New().StartOfLine().Find("http").Maybe("s").Find("://").Word()
+	assertStringEquals(v.GoSource(), `verbalexpressions.New().
+	StartOfLine().
+	Find("http").
+	Maybe("s").
+	Find("://").
+	Word()`, t)
+
+	v = New().SearchOneLine(true).Find("a").Digit().Times(2)
+	assertStringEquals(v.GoSource(), `verbalexpressions.New().SearchOneLine(true).Find("a").Digit().Times(2)`, t)
+
+	v = New().Raw(`\d+`).BeginNamedCapture("key").Word().EndCapture().
+		Group(func(g *VerbalExpression) {
+			g.Find(",").Class(NewClass().Letter().Chars("_"))
+		}).ZeroOrMore().
+		Either(New().Find("a"), New().WithAnyCase(true).Find("b")).
+		Or(New().Find("c"))
+	assertStringEquals(v.GoSource(), "verbalexpressions.New().\n"+
+		"\tRaw(`\\d+`).\n"+
+		`	BeginNamedCapture("key").
+	Word().
+	EndCapture().
+	Group(func(g *verbalexpressions.VerbalExpression) {
+		g.Find(",").Class(verbalexpressions.NewClass().Letter().Chars("_"))
+	}).
+	ZeroOrMore().
+	Either(
+		verbalexpressions.New().Find("a"),
+		verbalexpressions.New().WithAnyCase(true).Find("b"),
+	).
+	Or(verbalexpressions.New().Find("c"))`, t)
+}
+
+func TestGoSourceIsValidGo(t *testing.T) {
+
+	tests := []*VerbalExpression{
+		New(),
+		New().Group(func(*VerbalExpression) {}).Optional(),
+		New().CaseInsensitive(func(g *VerbalExpression) {
+			g.Find("a").CaseSensitive(func(g *VerbalExpression) {
+				g.Find("b").Either(New().Find("c").Or(New().Find("d")), New().Tab())
+			})
+		}),
+		New().Class(NewClass().UnicodeScript("Greek").Range('a', 'f').Not()).Find("\"\n`\\"),
+	}
+	for _, v := range tests {
+		if _, err := parser.ParseExpr(v.GoSource()); err != nil {
+			t.Errorf("%s is not valid Go: %v", v.GoSource(), err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+/* sub expressions built inline with closures */
+
+// build runs f on a new expression and returns its body, to be nested in
+// the receiver. The sub expression starts with the flags of the receiver, if
+// f changes them, they only apply to the sub expression.
+func (v *VerbalExpression) build(step string, f func(*VerbalExpression)) (node, *VerbalExpression) {
+	sub := New()
+	sub.flags = v.flags
+	f(sub)
+	if sub.err != nil {
+		return nil, v.fail(step, sub.err)
+	}
+
+	body := sub.body()
+	if on, off := sub.flags&^v.flags&regexFlags, v.flags&^sub.flags&regexFlags; on|off != 0 {
+		body = &group{on: on, off: off, body: body}
+	}
+
+	if name, dup := duplicateName(v, sub); dup {
+		return nil, v.fail(step, ErrDuplicateName, name)
+	}
+	return body, v
+}
+
+// Group builds a sub expression with f and adds it as a non capturing
+// group, so that it can be repeated as a whole:
+//
+//	// match "1.2.3.4"
+//	v.Range(0, 9).OneOrMore().Group(func(g *verbalexpressions.VerbalExpression) {
+//		g.Find(".").Range(0, 9).OneOrMore()
+//	}).Times(3)
+//
+// The expression given to f is a mutable one, created for the call.
+func (v *VerbalExpression) Group(f func(*VerbalExpression)) *VerbalExpression {
+	body, v := v.build("Group", f)
+	if body == nil {
+		return v
+	}
+	return v.add(&group{body: body})
+}
+
+// Capture builds a sub expression with f and captures it, as
+// BeginCapture() and EndCapture() would do around the calls made by f
+func (v *VerbalExpression) Capture(f func(*VerbalExpression)) *VerbalExpression {
+	body, v := v.build("Capture", f)
+	if body == nil {
+		return v
+	}
+	return v.add(&group{capture: true, body: body})
+}
+
+// Either matches one of the given expressions, at the current position of
+// the expression. Alternatives are tried in the given order, flags each one
+// changed from New() apply to itself only.
+//
+//	// match "http://" or "ftp://" at the begining of the line
+//	v.StartOfLine().Either(
+//		verbalexpressions.New().Find("http").Maybe("s"),
+//		verbalexpressions.New().Find("ftp"),
+//	).Find("://")
+func (v *VerbalExpression) Either(alts ...*VerbalExpression) *VerbalExpression {
+	if len(alts) == 0 {
+		return v.fail("Either", ErrNoAlternative)
+	}
+	args := make([]interface{}, len(alts))
+	for i, alt := range alts {
+		args[i] = alt
+	}
+
+	a := &alternation{}
+	for _, alt := range alts {
+		if _, err := alt.Compile(); err != nil {
+			return v.fail("Either", err, args...)
+		}
+		a.alts = append(a.alts, alt.embed())
+	}
+	// names are unique in the whole expression, not only in alternatives
+	if _, dup := duplicateName(append([]*VerbalExpression{v}, alts...)...); dup {
+		return v.fail("Either", ErrDuplicateName, args...)
+	}
+	return v.add(a)
+}
+
+// OneOf matches one of the given strings, at the current position of the
+// expression. Strings are tried in the given order.
+//
+//	// match "jpg", "jpeg" or "png" extension
+//	v.Find(".").OneOf("jpg", "jpeg", "png").EndOfLine()
+func (v *VerbalExpression) OneOf(literals ...string) *VerbalExpression {
+	if len(literals) == 0 {
+		return v.fail("OneOf", ErrNoAlternative)
+	}
+
+	a := &alternation{}
+	for _, s := range literals {
+		a.alts = append(a.alts, &literal{s})
+	}
+	return v.add(a)
+}
+
+// scoped adds the sub expression built by f in a group where flags "on"
+// are set and flags "off" are unset
+func (v *VerbalExpression) scoped(step string, on, off Flag, f func(*VerbalExpression)) *VerbalExpression {
+	body, v := v.build(step, f)
+	if body == nil {
+		return v
+	}
+	return v.add(&group{on: on, off: off, body: body})
+}
+
+// CaseInsensitive builds a sub expression with f that matches with or
+// without case sensitivity, as WithAnyCase(true) does for the whole
+// expression:
+//
+//	// match "Content-Type: text/html", "content-type: text/html"...
+//	v.CaseInsensitive(func(h *verbalexpressions.VerbalExpression) {
+//		h.Find("content-type")
+//	}).Find(": text/html")
+func (v *VerbalExpression) CaseInsensitive(f func(*VerbalExpression)) *VerbalExpression {
+	return v.scoped("CaseInsensitive", IGNORE_CASE, 0, f)
+}
+
+// CaseSensitive builds a sub expression with f that is case sensitive, even
+// if WithAnyCase(true) is set for the whole expression
+func (v *VerbalExpression) CaseSensitive(f func(*VerbalExpression)) *VerbalExpression {
+	return v.scoped("CaseSensitive", 0, IGNORE_CASE, f)
+}
+
+// DotMatchesNewline builds a sub expression with f where Anything() and
+// Something() match "\n" too, as MatchAllWithDot(true) does for the whole
+// expression
+func (v *VerbalExpression) DotMatchesNewline(f func(*VerbalExpression)) *VerbalExpression {
+	return v.scoped("DotMatchesNewline", DOTALL, 0, f)
+}
+
+// FindIgnoreCase seeks string "s" with or without case sensitivity, the
+// rest of the expression is not modified
+func (v *VerbalExpression) FindIgnoreCase(s string) *VerbalExpression {
+	return v.add(&group{on: IGNORE_CASE, body: &literal{s}})
+}
@@ -0,0 +1,209 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+
+	v := New().StartOfLine().Range(0, 9).OneOrMore().Group(func(g *VerbalExpression) {
+		g.Find(".").Range(0, 9).OneOrMore()
+	}).Times(3).EndOfLine()
+
+	assertStringEquals(v.String(), `(?m)^[0-9]+(?:\.[0-9]+){3}$`, t)
+	if !v.Test("192.168.0.1") {
+		t.Errorf("%v should match 192.168.0.1", v.Regex())
+	}
+	if v.Test("192.168.0") {
+		t.Errorf("%v should not match 192.168.0", v.Regex())
+	}
+}
+
+func TestCapture(t *testing.T) {
+
+	v := New().Find("<").Capture(func(c *VerbalExpression) {
+		c.Word().Capture(func(c *VerbalExpression) {
+			c.Find(":").Word()
+		}).Optional()
+	}).Find(">")
+
+	assertStringEquals(v.String(), `(?m)<(\w+(:\w+)?)>`, t)
+	res := v.Captures("<xml:tag>")
+	if len(res) != 1 || res[0][1] != "xml:tag" || res[0][2] != ":tag" {
+		t.Errorf("%v is not [[<xml:tag> xml:tag :tag]]", res)
+	}
+
+	// alternatives inside the group
+	v = New().Find("a").Capture(func(c *VerbalExpression) {
+		c.Find("b").Or(New().Find("c"))
+	})
+	assertStringEquals(v.String(), `(?m)a(b|c)`, t)
+}
+
+func TestGroupErrors(t *testing.T) {
+
+	v := New().Find("a").Group(func(g *VerbalExpression) {
+		g.Range("a")
+	})
+	err, ok := v.Err().(*StepError)
+	if !ok || err.Step != "Range" {
+		t.Errorf("%v is not the error of Range", v.Err())
+	}
+
+	v = New().BeginNamedCapture("a").EndCapture().Capture(func(c *VerbalExpression) {
+		c.BeginNamedCapture("a")
+	})
+	if !errors.Is(v.Err(), ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName", v.Err())
+	}
+
+	x := func() *VerbalExpression {
+		return New().Capture(func(c *VerbalExpression) { c.BeginNamedCapture("x").Digit().EndCapture() })
+	}
+	for _, v := range []*VerbalExpression{
+		New().Either(x(), x()),
+		New().Either(New().Find("a"), x(), New().Find("b"), x()),
+		New().BeginNamedCapture("x").EndCapture().Either(x(), New().Find("a")),
+	} {
+		if err, ok := v.Err().(*StepError); !ok || err.Step != "Either" || !errors.Is(err, ErrDuplicateName) {
+			t.Errorf("%v is not ErrDuplicateName of Either", v.Err())
+		}
+	}
+	if err := New().Either(x(), New().Find("a")).Err(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	base := New().Immutable().Find("a")
+	grouped := base.Group(func(g *VerbalExpression) { g.Find("b") }).OneOrMore()
+	assertStringEquals(base.String(), `(?m)a`, t)
+	assertStringEquals(grouped.String(), `(?m)ab+`, t)
+}
+
+func TestEither(t *testing.T) {
+
+	v := New().StartOfLine().Either(
+		New().Find("http").Maybe("s"),
+		New().Find("ftp"),
+	).Find("://")
+
+	assertStringEquals(v.String(), `(?m)^(?:https?|ftp)://`, t)
+	for s, expect := range map[string]bool{"https://": true, "ftp://": true, "sftp://": false, "http:/": false} {
+		if v.Test(s) != expect {
+			t.Errorf("%v matching %s should be %v", v.Regex(), s, expect)
+		}
+	}
+
+	// flags changed by alternatives apply to themselves only
+	v = New().Find("a").Either(
+		New().Find("b").WithAnyCase(true),
+		New().Find("c"),
+	).WithAnyCase(false).SearchOneLine(true)
+	assertStringEquals(v.String(), `a(?:(?i:b)|c)`, t)
+	if !v.Test("aB") || v.Test("aC") {
+		t.Errorf("%v should match aB and not aC", v.Regex())
+	}
+
+	if err := New().Either().Err(); !errors.Is(err, ErrNoAlternative) {
+		t.Errorf("%v is not ErrNoAlternative", err)
+	}
+	if err := New().Either(New().Find("a"), New().Range("a")).Err(); !errors.Is(err, ErrOddRangeArgs) {
+		t.Errorf("%v is not ErrOddRangeArgs", err)
+	}
+}
+
+func TestOneOf(t *testing.T) {
+
+	v := New().Find(".").OneOf("jpg", "jpeg", "png").EndOfLine()
+	assertStringEquals(v.String(), `(?m)\.(?:jpg|jpeg|png)$`, t)
+	if !v.Test("image.jpeg") || v.Test("image.gif") {
+		t.Errorf("%v should match image.jpeg and not image.gif", v.Regex())
+	}
+
+	v = New().OneOf("a+", "b").OneOrMore()
+	assertStringEquals(v.String(), `(?m)(?:a\+|b)+`, t)
+
+	if err := New().OneOf().Err(); !errors.Is(err, ErrNoAlternative) {
+		t.Errorf("%v is not ErrNoAlternative", err)
+	}
+}
+
+func TestOrWithAnchors(t *testing.T) {
+
+	v := New().
+		StartOfLine().Find("foo").EndOfLine().
+		Or(New().StartOfLine().Find("bar").EndOfLine())
+	assertStringEquals(v.String(), `(?m)^foo$|^bar$`, t)
+
+	// the alternative keeps the flags it changed
+	v = New().Find("c").WithAnyCase(true).Or(New().Find("b").SearchOneLine(true))
+	assertStringEquals(v.String(), `(?mi)c|(?-m:b)`, t)
+	if !v.Test("B") || !v.Test("C") {
+		t.Errorf("%v should match C and B", v.Regex())
+	}
+}
+
+func TestGroupFlags(t *testing.T) {
+
+	v := New().Find("a").Group(func(g *VerbalExpression) {
+		g.Find("b").WithAnyCase(true)
+	}).Find("c")
+	assertStringEquals(v.String(), `(?m)a(?i:b)c`, t)
+}
+
+func TestEmbedFlags(t *testing.T) {
+
+	// flags set after And() and Either() apply to their expressions too
+	v := New().Find("x").And(New().Find("a")).WithAnyCase(true)
+	assertStringEquals(v.String(), `(?mi)xa`, t)
+	if !v.Test("XA") {
+		t.Errorf("%v should match XA", v.Regex())
+	}
+
+	v = New().Find("x").Either(New().Find("a"), New().Find("b")).WithAnyCase(true)
+	assertStringEquals(v.String(), `(?mi)x(?:a|b)`, t)
+	if !v.Test("XA") || !v.Test("XB") {
+		t.Errorf("%v should match XA and XB", v.Regex())
+	}
+
+	// but not to the ones changing them
+	v = New().Find("x").And(New().Find("a").MatchAllWithDot(true).Anything()).
+		Either(New().Find("b").SearchOneLine(true), New().Find("c").WithAnyCase(true)).
+		WithAnyCase(true)
+	assertStringEquals(v.String(), `(?mi)x(?s:a.*)(?:(?-m:b)|c)`, t)
+}
+
+func TestScopedFlags(t *testing.T) {
+
+	v := New().CaseInsensitive(func(h *VerbalExpression) {
+		h.Find("content-type")
+	}).Find(": text/html")
+	assertStringEquals(v.String(), `(?m)(?i:content-type): text/html`, t)
+	if !v.Test("Content-Type: text/html") || v.Test("Content-Type: TEXT/HTML") {
+		t.Errorf("%v should only ignore case of the header name", v.Regex())
+	}
+
+	v = New().FindIgnoreCase("header").Find("X").OneOrMore()
+	assertStringEquals(v.String(), `(?m)(?i:header)X+`, t)
+	if !v.Test("HEADERX") || v.Test("HEADERx") {
+		t.Errorf("%v should match HEADERX and not HEADERx", v.Regex())
+	}
+
+	// nothing to do if the flag is already set
+	v = New().WithAnyCase(true).FindIgnoreCase("a").CaseSensitive(func(s *VerbalExpression) {
+		s.Find("b")
+	})
+	assertStringEquals(v.String(), `(?mi)a(?-i:b)`, t)
+
+	v = New().Find("<").DotMatchesNewline(func(d *VerbalExpression) {
+		d.Anything().Lazy()
+	}).Find(">").Anything()
+	assertStringEquals(v.String(), `(?m)<(?s:.*?)>.*`, t)
+	assertStringEquals(v.Regex().FindString("<a\nb>c\nd"), "<a\nb>c", t)
+
+	v = New().FindIgnoreCase("ab").Optional()
+	assertStringEquals(v.String(), `(?m)(?i:ab)?`, t)
+}
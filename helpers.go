@@ -2,6 +2,11 @@ package verbalexpressions
 
 /* proxy and helpers to regexp.Regexp functions */
 
+/*
+Helpers only read the expression: they are safe for concurrent use, as long as
+no builder method is called on the same VerbalExpression at the same time.
+*/
+
 // Test return true if verbalexpressions matches something in string "s"
 func (v *VerbalExpression) Test(s string) bool {
 	return v.Regex().Match([]byte(s))
@@ -22,3 +27,53 @@ func (v *VerbalExpression) Captures(s string) [][]string {
 	}
 	return v.Regex().FindAllStringSubmatch(s, iter)
 }
+
+// CapturesIndex is Captures with the byte offsets of matches and groups in
+// "s" instead of their text, as regexp.FindAllStringSubmatchIndex. Groups
+// that took no part in a match have -1 offsets.
+func (v *VerbalExpression) CapturesIndex(s string) [][]int {
+	iter := 1
+	if v.flags&GLOBAL != 0 {
+		iter = -1
+	}
+	return v.Regex().FindAllStringSubmatchIndex(s, iter)
+}
+
+// NamedCaptures returns, for each match, a map of named groups (see
+// BeginNamedCapture) to the text they captured. Unnamed groups are not in
+// the maps. As Captures, it returns only the first match if StopAtFirst(true)
+// is set.
+func (v *VerbalExpression) NamedCaptures(s string) []map[string]string {
+	r := v.Regex()
+	names := r.SubexpNames()
+
+	res := []map[string]string{}
+	for _, match := range v.Captures(s) {
+		m := make(map[string]string)
+		for i, name := range names {
+			if name != "" {
+				m[name] = match[i]
+			}
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// CaptureMap returns named groups of the first match in string "s", or nil
+// if the expression doesn't match
+func (v *VerbalExpression) CaptureMap(s string) map[string]string {
+	r := v.Regex()
+	match := r.FindStringSubmatch(s)
+	if match == nil {
+		return nil
+	}
+
+	m := make(map[string]string)
+	for i, name := range r.SubexpNames() {
+		if name != "" {
+			m[name] = match[i]
+		}
+	}
+	return m
+}
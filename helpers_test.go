@@ -0,0 +1,22 @@
+package verbalexpressions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapturesIndex(t *testing.T) {
+
+	v := New().BeginCapture().Digit().EndCapture().Maybe("x")
+	s := "a1b2x"
+	expect := [][]int{{1, 2, 1, 2}, {3, 5, 3, 4}}
+	if got := v.CapturesIndex(s); !reflect.DeepEqual(got, expect) {
+		t.Errorf("%v is not %v", got, expect)
+	}
+	if got := v.StopAtFirst(true).CapturesIndex(s); !reflect.DeepEqual(got, expect[:1]) {
+		t.Errorf("%v is not %v", got, expect[:1])
+	}
+	if got := v.CapturesIndex("none"); got != nil {
+		t.Errorf("%v is not nil", got)
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+/* expressions saved as JSON lists of steps */
+
+// jsonChain is the JSON form of an expression, or of the body of a closure
+// or a class. Flags are the names of the flags of an expression, if they
+// are not given, the flags are the ones of New().
+type jsonChain struct {
+	Flags *[]string  `json:"flags,omitempty"`
+	Steps []jsonStep `json:"steps"`
+}
+
+// jsonStep is the JSON form of a step, the call of a builder method
+type jsonStep struct {
+	Step string            `json:"step"`
+	Args []json.RawMessage `json:"args,omitempty"`
+}
+
+// jsonFlags are JSON names of flags
+var jsonFlags = []struct {
+	flag Flag
+	name string
+}{
+	{MULTILINE, "multiline"},
+	{IGNORE_CASE, "ignore_case"},
+	{DOTALL, "dotall"},
+	{UNGREEDY, "ungreedy"},
+	{GLOBAL, "global"},
+}
+
+// MarshalJSON returns the expression as the list of builder methods to
+// call, in order, with their arguments:
+//
+//	{
+//		"flags": ["multiline", "global"],
+//		"steps": [
+//			{"step": "StartOfLine"},
+//			{"step": "Find", "args": ["http"]},
+//			{"step": "Maybe", "args": ["s"]}
+//		]
+//	}
+//
+// Arguments of Group(), Capture() and other methods taking a function are
+// lists of steps, as well as CharClass arguments, {"steps": [...]}. Steps
+// may differ from the calls that built the expression, but they make the
+// same regular expression. An expression with an error, see Err(), can't be
+// marshaled.
+func (v *VerbalExpression) MarshalJSON() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	c, err := encodeChain(v.chain())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(c)
+}
+
+// UnmarshalJSON replaces the expression by the one made by steps of a JSON
+// document returned by MarshalJSON(). Unknown steps, flags or fields and
+// invalid arguments are reported as errors, as well as the error of a step
+// that fails.
+func (v *VerbalExpression) UnmarshalJSON(data []byte) error {
+	var c jsonChain
+	if err := decodeStrict(data, &c); err != nil {
+		return err
+	}
+	ch, err := decodeChain(c)
+	if err != nil {
+		return err
+	}
+	e := ch.build()
+	if e.err != nil {
+		return e.err
+	}
+
+	v.invalidate()
+	v.root, v.open, v.alternatives, v.flags, v.err = e.root, e.open, e.alternatives, e.flags, nil
+	return nil
+}
+
+// MarshalText returns the JSON document of MarshalJSON(), so expressions can
+// be saved by encoders using encoding.TextMarshaler
+func (v *VerbalExpression) MarshalText() ([]byte, error) {
+	return v.MarshalJSON()
+}
+
+// UnmarshalText reads a JSON document returned by MarshalText()
+func (v *VerbalExpression) UnmarshalText(text []byte) error {
+	return v.UnmarshalJSON(text)
+}
+
+// decodeStrict decodes data to v, unknown fields are errors
+func decodeStrict(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	return d.Decode(v)
+}
+
+// encodeChain returns the JSON form of c
+func encodeChain(c chain) (jsonChain, error) {
+	names := []string{}
+	for _, f := range jsonFlags {
+		if c.flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	steps, err := encodeSteps(c.steps)
+	return jsonChain{Flags: &names, Steps: steps}, err
+}
+
+// encodeSteps returns the JSON form of steps
+func encodeSteps(steps []step) ([]jsonStep, error) {
+	result := []jsonStep{}
+	for _, s := range flatten(steps) {
+		js := jsonStep{Step: s.name}
+		for _, arg := range s.args {
+			var value interface{}
+			var err error
+			switch arg := arg.(type) {
+			case chain:
+				value, err = encodeChain(arg)
+			case closure:
+				value, err = encodeBody(arg)
+			case classChain:
+				value, err = encodeBody(arg)
+			case rune:
+				value = string(arg)
+			default:
+				value = arg
+			}
+			if err != nil {
+				return nil, err
+			}
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			js.Args = append(js.Args, raw)
+		}
+		result = append(result, js)
+	}
+	return result, nil
+}
+
+// encodeBody returns the JSON form of steps of a closure or a class
+func encodeBody(steps []step) (jsonChain, error) {
+	s, err := encodeSteps(steps)
+	return jsonChain{Steps: s}, err
+}
+
+// decodeChain returns the chain of an expression from its JSON form
+func decodeChain(c jsonChain) (chain, error) {
+	ch := chain{flags: MULTILINE | GLOBAL}
+	if c.Flags != nil {
+		ch.flags = 0
+	flags:
+		for _, name := range *c.Flags {
+			for _, f := range jsonFlags {
+				if f.name == name {
+					ch.flags |= f.flag
+					continue flags
+				}
+			}
+			return chain{}, &StepError{Step: "flags", Args: []interface{}{name}, Err: ErrUnknownFlag}
+		}
+	}
+	steps, err := decodeSteps(expressionType, c.Steps)
+	ch.steps = steps
+	return ch, err
+}
+
+// decodeBody returns the steps of a closure or a class from their JSON form
+func decodeBody(receiver reflect.Type, c jsonChain) ([]step, error) {
+	if c.Flags != nil {
+		return nil, &StepError{Step: "flags", Err: ErrStepArgs}
+	}
+	return decodeSteps(receiver, c.Steps)
+}
+
+// decodeSteps returns steps calling methods of receiver, arguments are
+// checked against the method signatures
+func decodeSteps(receiver reflect.Type, steps []jsonStep) ([]step, error) {
+	result := []step{}
+	for _, js := range steps {
+		args := make([]interface{}, len(js.Args))
+		for i, raw := range js.Args {
+			// decoded for error messages only
+			json.Unmarshal(raw, &args[i])
+		}
+		serr := &StepError{Step: js.Step, Args: args, Err: ErrStepArgs}
+
+		m, ok := stepMethod(receiver, js.Step)
+		if !ok {
+			serr.Err = ErrUnknownStep
+			return nil, serr
+		}
+		if !takesArgs(m, len(js.Args)) {
+			return nil, serr
+		}
+
+		s := step{name: js.Step}
+		for i, raw := range js.Args {
+			arg, err := decodeArg(paramType(m, i), raw)
+			if err != nil {
+				if se, ok := err.(*StepError); ok {
+					return nil, se
+				}
+				return nil, serr
+			}
+			s.args = append(s.args, arg)
+		}
+		result = append(result, s)
+	}
+	nested, rest := nest(result)
+	if rest != nil {
+		return nil, &StepError{Step: "EndCapture", Err: ErrNoOpenGroup}
+	}
+	return nested, nil
+}
+
+// decodeArg returns the argument of type t from its JSON form
+func decodeArg(t reflect.Type, raw json.RawMessage) (interface{}, error) {
+	switch t {
+	case expressionType:
+		var c jsonChain
+		if err := decodeStrict(raw, &c); err != nil {
+			return nil, err
+		}
+		return decodeChain(c)
+	case closureType, classType:
+		var c jsonChain
+		if err := decodeStrict(raw, &c); err != nil {
+			return nil, err
+		}
+		receiver := expressionType
+		if t == classType {
+			receiver = classType
+		}
+		steps, err := decodeBody(receiver, c)
+		if t == classType {
+			return classChain(steps), err
+		}
+		return closure(steps), err
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case reflect.Int32:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if utf8.RuneCountInString(s) != 1 {
+			return nil, ErrStepArgs
+		}
+		r, _ := utf8.DecodeRuneInString(s)
+		return r, nil
+	case reflect.Int:
+		var i int
+		err := json.Unmarshal(raw, &i)
+		return i, err
+	case reflect.Bool:
+		var b bool
+		err := json.Unmarshal(raw, &b)
+		return b, err
+	case reflect.Interface:
+		// Range() arguments, strings or integers
+		var s string
+		if strings.HasPrefix(string(bytes.TrimSpace(raw)), `"`) {
+			err := json.Unmarshal(raw, &s)
+			return s, err
+		}
+		var i int
+		err := json.Unmarshal(raw, &i)
+		return i, err
+	}
+	return nil, ErrStepArgs
+}
@@ -0,0 +1,128 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+
+	tests := []*VerbalExpression{
+		New(),
+		New().StartOfLine().Find("http").Maybe("s").Find("://").Word().EndOfLine(),
+		New().SearchOneLine(true).WithAnyCase(true).MatchAllWithDot(true).Ungreedy(true).StopAtFirst(true).Anything(),
+		New().Not("abc").LineBreak().Tab().WholeWord("cat").Multiple("x", 2, 5).Multiple("y", 3).Multiple("z"),
+		New().Find("a").Not("").Find("b"),
+		New().Any("xyz").Range("a", "f", 0, 9).Digit().Whitespace().Letter().HexDigit().Punctuation(),
+		New().Class(NewClass().Letter().Digit().Chars("_-").Range('α', 'ω').Not()),
+		New().UnicodeScript("Han").UnicodeCategory("Lu").UnicodeWord(),
+		New().BeginCapture().Find("a").BeginNamedCapture("b").Digit().EndCapture().EndCapture().Optional(),
+		New().Group(func(g *VerbalExpression) { g.Find("a").Digit() }).OneOrMore().Lazy(),
+		New().CaseInsensitive(func(g *VerbalExpression) {
+			g.Find("a").CaseSensitive(func(g *VerbalExpression) { g.Find("b") })
+		}).FindIgnoreCase("c"),
+		New().Either(New().Find("a"), New().SearchOneLine(true).StartOfLine()).OneOf("d", "e"),
+		New().Find("a").And(New().WithAnyCase(true).Find("b")).Or(New().MatchAllWithDot(true).Anything()),
+		New().Raw(`(\d)+`).Optional(),
+	}
+	for _, v := range tests {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Errorf("%s: %v", v.GoSource(), err)
+			continue
+		}
+		r := New()
+		if err := json.Unmarshal(data, r); err != nil {
+			t.Errorf("%s: %v", data, err)
+			continue
+		}
+		assertStringEquals(r.String(), v.String(), t)
+
+		text, err := v.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = New()
+		if err := r.UnmarshalText(text); err != nil {
+			t.Errorf("%s: %v", text, err)
+		}
+		assertStringEquals(r.String(), v.String(), t)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+
+	v := New().StartOfLine().Find("http").Maybe("s").Range("a", "c")
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"flags":["multiline","global"],"steps":[{"step":"StartOfLine"},{"step":"Find","args":["http"]},` +
+		`{"step":"Maybe","args":["s"]},{"step":"Range","args":["a","c"]}]}`
+	assertStringEquals(string(data), expect, t)
+
+	// flags default to the ones of New()
+	r := New().Find("x")
+	if err := json.Unmarshal([]byte(`{"steps":[{"step":"Find","args":["a"]}]}`), r); err != nil {
+		t.Fatal(err)
+	}
+	assertStringEquals(r.String(), "(?m)a", t)
+}
+
+func TestJSONField(t *testing.T) {
+
+	type config struct {
+		Name    string
+		Pattern *VerbalExpression
+	}
+	c := config{"version", New().Find("v").Digit().OneOrMore()}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r config
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	assertStringEquals(r.Pattern.String(), c.Pattern.String(), t)
+}
+
+func TestJSONErrors(t *testing.T) {
+
+	tests := []struct {
+		data string
+		err  error
+	}{
+		{`{"steps":[{"step":"Explode"}]}`, ErrUnknownStep},
+		{`{"steps":[{"step":"Clone"}]}`, ErrUnknownStep},
+		{`{"steps":[{"step":"Regex"}]}`, ErrUnknownStep},
+		{`{"steps":[{"step":"Find"}]}`, ErrStepArgs},
+		{`{"steps":[{"step":"Find","args":[1]}]}`, ErrStepArgs},
+		{`{"steps":[{"step":"Times","args":["a"]}]}`, ErrStepArgs},
+		{`{"steps":[{"step":"Class","args":[{"steps":[{"step":"Find","args":["a"]}]}]}]}`, ErrUnknownStep},
+		{`{"steps":[{"step":"Group","args":[{"steps":[{"step":"Nope"}]}]}]}`, ErrUnknownStep},
+		{`{"flags":["sticky"],"steps":[]}`, ErrUnknownFlag},
+		{`{"steps":[{"step":"Find","args":["a"]},{"step":"EndCapture"}]}`, ErrNoOpenGroup},
+		{`{"steps":[{"step":"Find","args":["a"]},{"step":"Times","args":[-1]}]}`, ErrInvalidRepeat},
+	}
+	for _, test := range tests {
+		v := New()
+		err := json.Unmarshal([]byte(test.data), v)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: error is %v, not %v", test.data, err, test.err)
+		}
+	}
+
+	// unknown fields
+	if err := json.Unmarshal([]byte(`{"steps":[],"pattern":"a"}`), New()); err == nil {
+		t.Error("unknown field is not an error")
+	}
+	// expressions with an error can't be saved
+	if _, err := json.Marshal(New().Times(-1)); err == nil {
+		t.Error("expression with an error is marshaled")
+	}
+}
@@ -0,0 +1,33 @@
+package patterns_test
+
+import (
+	"fmt"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+	"github.com/VerbalExpressions/GoVerbalExpressions/patterns"
+)
+
+func ExampleIPv4() {
+	// an address and its port
+	v := verbalexpressions.New().StartOfInput().
+		And(patterns.IPv4()).Find(":").
+		BeginNamedCapture("port").Digit().OneOrMore().EndCapture().
+		EndOfInput()
+
+	fmt.Println(v.Test("192.168.1.1:8080"))
+	fmt.Println(v.Test("192.168.1.256:8080"))
+	fmt.Println(v.CaptureMap("10.0.0.1:22")["port"])
+	// Output:
+	// true
+	// false
+	// 22
+}
+
+func ExampleUUID() {
+	v := verbalexpressions.New().Find("id=").And(patterns.UUID(4))
+	fmt.Println(v.Test("id=550e8400-e29b-41d4-a716-446655440000"))
+	fmt.Println(v.Test("id=f81d4fae-7dec-11d0-a765-00a0c91e6bf6"))
+	// Output:
+	// true
+	// false
+}
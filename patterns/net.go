@@ -0,0 +1,138 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package patterns
+
+import (
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// IPv4 matches an IPv4 address in dotted decimal, as "192.168.1.1". Numbers
+// are 0 to 255, without leading zeros.
+func IPv4() *verbalexpressions.VerbalExpression {
+	octet := func() *verbalexpressions.VerbalExpression {
+		return newExpr().Either(
+			newExpr().Find("25").Range(0, 5),
+			newExpr().Find("2").Range(0, 4).Digit(),
+			newExpr().Find("1").Digit().Times(2),
+			newExpr().Range(1, 9).Optional().Digit(),
+		)
+	}
+	return newExpr().And(octet()).Group(func(g *verbalexpressions.VerbalExpression) {
+		g.Find(".").And(octet())
+	}).Times(3)
+}
+
+// IPv6 matches an IPv6 address of RFC 4291, as "2001:db8::8a2e:370:7334",
+// "::1" or "::ffff:192.0.2.1", with zeros compressed by "::" and the last
+// 32 bits written as an IPv4 address, if they are. Zone identifiers, as
+// "%eth0", are not matched.
+func IPv6() *verbalexpressions.VerbalExpression {
+	h16 := func() *verbalexpressions.VerbalExpression {
+		return newExpr().HexDigit().Between(1, 4)
+	}
+	// groups adds min to max groups of v, each followed by a colon
+	groups := func(v *verbalexpressions.VerbalExpression, min, max int) *verbalexpressions.VerbalExpression {
+		if max == 0 {
+			return v
+		}
+		return v.Group(func(g *verbalexpressions.VerbalExpression) {
+			g.And(h16()).Find(":")
+		}).Between(min, max)
+	}
+
+	alts := []*verbalexpressions.VerbalExpression{
+		groups(newExpr(), 6, 6).And(IPv4()),
+		groups(newExpr(), 7, 7).And(h16()),
+	}
+	// "::" replaces one group at least, of the 8 groups, or 6 and an IPv4
+	// address. Longer left parts come first, to match them all.
+	for left := 7; left >= 0; left-- {
+		v := newExpr()
+		if left > 0 {
+			v = groups(v, left-1, left-1).And(h16())
+		}
+		v = v.Find("::")
+		if right := 7 - left; right > 0 {
+			tails := []*verbalexpressions.VerbalExpression{}
+			if right >= 2 {
+				tails = append(tails, groups(newExpr(), 0, right-2).And(IPv4()))
+			}
+			tails = append(tails, groups(newExpr(), 0, right-1).And(h16()))
+			v = v.Either(tails...).Optional()
+		}
+		alts = append(alts, v)
+	}
+	return newExpr().Either(alts...)
+}
+
+// MAC matches a MAC address, six pairs of hexadecimal digits separated by
+// colons, "00:1a:2b:3c:4d:5e", or by hyphens, "00-1A-2B-3C-4D-5E"
+func MAC() *verbalexpressions.VerbalExpression {
+	mac := func(sep string) *verbalexpressions.VerbalExpression {
+		return newExpr().HexDigit().Times(2).Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Find(sep).HexDigit().Times(2)
+		}).Times(5)
+	}
+	return newExpr().Either(mac(":"), mac("-"))
+}
+
+// label matches a label of a domain name: up to 63 letters, digits and
+// hyphens, not at its ends
+func label() *verbalexpressions.VerbalExpression {
+	return newExpr().Class(verbalexpressions.NewClass().Letter().Digit()).Group(func(g *verbalexpressions.VerbalExpression) {
+		g.Class(verbalexpressions.NewClass().Letter().Digit().Chars("-")).Between(0, 61).
+			Class(verbalexpressions.NewClass().Letter().Digit())
+	}).Optional()
+}
+
+// hostname matches a domain name of at least min labels
+func hostname(min int) *verbalexpressions.VerbalExpression {
+	return newExpr().And(label()).Group(func(g *verbalexpressions.VerbalExpression) {
+		g.Find(".").And(label())
+	}).AtLeast(min - 1)
+}
+
+// Email matches an email address, as "john.doe+news@example.com": the
+// local part is made of the characters allowed by RFC 5322 without quotes,
+// separated by dots, the domain has two labels at least. Quoted local parts
+// and IP addresses as domains are not matched.
+func Email() *verbalexpressions.VerbalExpression {
+	atext := verbalexpressions.NewClass().Letter().Digit().Chars("!#$%&'*+/=?^_`{|}~-")
+	return newExpr().Class(atext).OneOrMore().Group(func(g *verbalexpressions.VerbalExpression) {
+		g.Find(".").Class(atext).OneOrMore()
+	}).ZeroOrMore().Find("@").And(hostname(2))
+}
+
+// URL matches a http, https or ftp URL, as
+// "https://example.com:8080/path?query#fragment". The host is a domain
+// name, as "localhost", or an IPv6 address in brackets. The path, query and
+// fragment end at spaces, quotes and angle brackets, to find URLs in text.
+// User names and passwords are not matched.
+func URL() *verbalexpressions.VerbalExpression {
+	// characters of the path, query and fragment, but sep
+	text := func(sep string) *verbalexpressions.CharClass {
+		return verbalexpressions.NewClass().Whitespace().Chars(`"<>` + sep).Not()
+	}
+	return newExpr().
+		CaseInsensitive(func(g *verbalexpressions.VerbalExpression) {
+			g.OneOf("https", "http", "ftp")
+		}).
+		Find("://").
+		Either(
+			newExpr().Find("[").And(IPv6()).Find("]"),
+			hostname(1),
+		).
+		Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Find(":").Digit().Between(1, 5)
+		}).Optional().
+		Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Find("/").Class(text("?#")).ZeroOrMore()
+		}).Optional().
+		Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Find("?").Class(text("#")).ZeroOrMore()
+		}).Optional().
+		Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Find("#").Class(text("")).ZeroOrMore()
+		}).Optional()
+}
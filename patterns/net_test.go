@@ -0,0 +1,163 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package patterns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIPv4(t *testing.T) {
+
+	patternTest{
+		valid: []string{
+			"0.0.0.0",
+			"127.0.0.1",
+			"192.168.1.255",
+			"255.255.255.255",
+			"10.99.100.249",
+		},
+		invalid: []string{
+			"",
+			"1.2.3",
+			"1.2.3.4.5",
+			"256.1.1.1",
+			"1.1.1.300",
+			"01.2.3.4",
+			"1.2.3.04",
+			"1..2.3",
+			"1.2.3.4.",
+			"a.b.c.d",
+			"1.2.3.-4",
+		},
+	}.check(t, "IPv4()", IPv4())
+}
+
+func TestIPv6(t *testing.T) {
+
+	patternTest{
+		valid: []string{
+			"2001:0db8:85a3:0000:0000:8a2e:0370:7334",
+			"2001:db8:85a3::8a2e:370:7334",
+			"FE80::1",
+			"::1",
+			"::",
+			"1::",
+			"1:2:3:4:5:6:7::",
+			"::2:3:4:5:6:7:8",
+			"1::8",
+			"1:2::7:8",
+			"::ffff:192.0.2.1",
+			"64:ff9b::192.0.2.33",
+			"1:2:3:4:5:6:192.0.2.1",
+			"1::4:5:6:192.0.2.1",
+		},
+		invalid: []string{
+			"",
+			"1:2:3:4:5:6:7",
+			"1:2:3:4:5:6:7:8:9",
+			"1:2:3:4:5:6:7:8::",
+			"1::2::3",
+			":::",
+			"1:::2",
+			"12345::",
+			"g::1",
+			"1:2:3:4:5:6:7:192.0.2.1",
+			"::1:2:3:4:5:6:192.0.2.1",
+			"::ffff:256.0.2.1",
+			"fe80::1%eth0",
+			":1:2:3:4:5:6:7",
+		},
+	}.check(t, "IPv6()", IPv6())
+}
+
+func TestMAC(t *testing.T) {
+
+	patternTest{
+		valid: []string{
+			"00:1a:2b:3c:4d:5e",
+			"00-1A-2B-3C-4D-5E",
+			"ff:ff:ff:ff:ff:ff",
+		},
+		invalid: []string{
+			"",
+			"00:1a:2b:3c:4d",
+			"00:1a:2b:3c:4d:5e:6f",
+			"00:1a-2b:3c:4d:5e",
+			"001a.2b3c.4d5e",
+			"0:1a:2b:3c:4d:5e",
+			"00:1g:2b:3c:4d:5e",
+		},
+	}.check(t, "MAC()", MAC())
+}
+
+func TestEmail(t *testing.T) {
+
+	patternTest{
+		valid: []string{
+			"john@example.com",
+			"john.doe+news@example.co.uk",
+			"x@a.io",
+			"o'brien@mail.example-site.org",
+			"!#$%&'*+/=?^_`{|}~-@example.com",
+			"user@" + strings.Repeat("a", 63) + ".com",
+		},
+		invalid: []string{
+			"",
+			"john",
+			"john@",
+			"@example.com",
+			"john@localhost",
+			"john..doe@example.com",
+			".john@example.com",
+			"john.@example.com",
+			"john doe@example.com",
+			"john@-example.com",
+			"john@example-.com",
+			"john@example..com",
+			"john@@example.com",
+			"user@" + strings.Repeat("a", 64) + ".com",
+		},
+	}.check(t, "Email()", Email())
+}
+
+func TestURL(t *testing.T) {
+
+	patternTest{
+		valid: []string{
+			"http://example.com",
+			"https://example.com/",
+			"HTTPS://Example.COM/Path",
+			"ftp://files.example.com/pub/file.tar.gz",
+			"http://localhost:8080",
+			"https://example.com:443/a/b?q=1&r=2#top",
+			"http://example.com?q",
+			"http://example.com#frag",
+			"http://192.168.1.1/",
+			"http://[::1]:8080/",
+			"http://[2001:db8::1]/index.html",
+		},
+		invalid: []string{
+			"",
+			"example.com",
+			"mailto:john@example.com",
+			"file:///etc/passwd",
+			"http://",
+			"http:/example.com",
+			"http://exa mple.com",
+			"http://-example.com",
+			"http://example.com:123456",
+			"http://example.com/a b",
+			"http://[::1",
+			"http://user@example.com",
+		},
+	}.check(t, "URL()", URL())
+
+	// URLs in a text end at spaces and quotes
+	v := URL()
+	got := v.Captures(`see <https://example.com/doc> or "http://example.org/?a=b".`)
+	if len(got) != 2 || got[0][0] != "https://example.com/doc" || got[1][0] != "http://example.org/?a=b" {
+		t.Errorf("matches are %q", got)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+
+// Package patterns provides verbal expressions of common formats: IP and
+// MAC addresses, emails, URLs, UUIDs, ISO-8601 dates and semantic versions.
+//
+// Patterns are not anchored, they match anywhere in the text: add them to
+// another expression with And(), between StartOfInput() and EndOfInput()
+// to validate a whole string, or between WordBoundary() to search a text.
+//
+//	// "192.168.1.1:8080"
+//	v := verbalexpressions.New().StartOfInput().
+//		And(patterns.IPv4()).Find(":").Digit().OneOrMore().
+//		EndOfInput()
+//
+// Patterns have no capture groups, so that the groups of the expression
+// they are added to keep their numbers. Each call returns a new
+// expression.
+package patterns
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// newExpr is verbalexpressions.New, for short
+func newExpr() *verbalexpressions.VerbalExpression {
+	return verbalexpressions.New()
+}
+
+// UUID matches a UUID of RFC 9562 of the given version, as
+// "f81d4fae-7dec-11d0-a765-00a0c91e6bf6" for version 1, in lower or upper
+// case. Versions 1 to 8 also check the variant of the UUID. Version 0, or
+// a version out of 1 to 15, matches any UUID, as the nil UUID.
+func UUID(version int) *verbalexpressions.VerbalExpression {
+	hex := func(n int) *verbalexpressions.VerbalExpression {
+		return newExpr().HexDigit().Times(n)
+	}
+	v := newExpr().And(hex(8)).Find("-").And(hex(4)).Find("-")
+	if version < 1 || version > 15 {
+		return v.And(hex(4)).Find("-").And(hex(4)).Find("-").And(hex(12))
+	}
+	if d := strconv.FormatInt(int64(version), 16); version < 10 {
+		v = v.Find(d)
+	} else {
+		v = v.Any(d + strings.ToUpper(d))
+	}
+	v = v.And(hex(3)).Find("-")
+	if version <= 8 {
+		// variant 10xx
+		v = v.Any("89abAB")
+	} else {
+		v = v.HexDigit()
+	}
+	return v.And(hex(3)).Find("-").And(hex(12))
+}
+
+// number matches an integer without leading zero: 0, 7, 42, but not 07
+func number() *verbalexpressions.VerbalExpression {
+	return newExpr().Either(
+		newExpr().Range(1, 9).Digit().ZeroOrMore(),
+		newExpr().Find("0"),
+	)
+}
+
+// SemVer matches a semantic version 2.0.0, as "1.2.3",
+// "1.0.0-alpha.1" or "1.0.0+build.5". Numbers can't have leading zeros
+// and there is no "v" prefix.
+func SemVer() *verbalexpressions.VerbalExpression {
+	alnum := verbalexpressions.NewClass().Letter().Digit().Chars("-")
+	identifiers := func(ident *verbalexpressions.VerbalExpression) *verbalexpressions.VerbalExpression {
+		return newExpr().And(ident).Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Find(".").And(ident)
+		}).ZeroOrMore()
+	}
+	// numeric identifiers of pre-releases can't have leading zeros
+	prerelease := newExpr().Either(
+		newExpr().Digit().ZeroOrMore().Class(verbalexpressions.NewClass().Letter().Chars("-")).Class(alnum).ZeroOrMore(),
+		number(),
+	)
+	build := newExpr().Class(alnum).OneOrMore()
+
+	return newExpr().
+		And(number()).Find(".").And(number()).Find(".").And(number()).
+		Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Find("-").And(identifiers(prerelease))
+		}).Optional().
+		Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Find("+").And(identifiers(build))
+		}).Optional()
+}
+
+// ISO8601 matches a date of ISO 8601, in the extended format of RFC 3339:
+// "2013-07-24", with an optional time, "2013-07-24T18:30", seconds and
+// their fraction, "2013-07-24T18:30:05.123", and time zone,
+// "2013-07-24T18:30:05Z" or "2013-07-24T18:30:05+02:00". Months and days
+// are checked, not the number of days of a month.
+func ISO8601() *verbalexpressions.VerbalExpression {
+	month := newExpr().Either(
+		newExpr().Find("0").Range(1, 9),
+		newExpr().Find("1").Range(0, 2),
+	)
+	day := newExpr().Either(
+		newExpr().Find("0").Range(1, 9),
+		newExpr().Range(1, 2).Digit(),
+		newExpr().Find("3").Range(0, 1),
+	)
+	hour := newExpr().Either(
+		newExpr().Range(0, 1).Digit(),
+		newExpr().Find("2").Range(0, 3),
+	)
+	minute := newExpr().Range(0, 5).Digit()
+	// with leap seconds
+	second := newExpr().Either(minute, newExpr().Find("60"))
+	zone := newExpr().Either(
+		newExpr().Any("Zz"),
+		newExpr().Any("+-").And(hour).Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Maybe(":").And(minute)
+		}).Optional(),
+	)
+
+	return newExpr().
+		Digit().Times(4).Find("-").And(month).Find("-").And(day).
+		Group(func(g *verbalexpressions.VerbalExpression) {
+			g.Any("Tt").And(hour).Find(":").And(minute).
+				Group(func(g *verbalexpressions.VerbalExpression) {
+					g.Find(":").And(second).Group(func(g *verbalexpressions.VerbalExpression) {
+						g.Any(".,").Digit().OneOrMore()
+					}).Optional()
+				}).Optional().
+				And(zone).Optional()
+		}).Optional()
+}
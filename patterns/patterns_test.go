@@ -0,0 +1,177 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package patterns
+
+import (
+	"testing"
+
+	"github.com/VerbalExpressions/GoVerbalExpressions"
+)
+
+// patternTest are strings matched, or not, by a pattern as a whole
+type patternTest struct {
+	valid, invalid []string
+}
+
+// check checks that the whole strings of test match p, or not
+func (test patternTest) check(t *testing.T, name string, p *verbalexpressions.VerbalExpression) {
+	t.Helper()
+	if err := p.Err(); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	v := verbalexpressions.New().StartOfInput().And(p).EndOfInput()
+	for _, s := range test.valid {
+		if !v.Test(s) {
+			t.Errorf("%s doesn't match %q", name, s)
+		}
+	}
+	for _, s := range test.invalid {
+		if v.Test(s) {
+			t.Errorf("%s matches %q", name, s)
+		}
+	}
+}
+
+func TestUUID(t *testing.T) {
+
+	patternTest{
+		valid: []string{
+			"f81d4fae-7dec-11d0-a765-00a0c91e6bf6",
+			"00000000-0000-0000-0000-000000000000",
+			"FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF",
+			"550e8400-e29b-41d4-a716-446655440000",
+		},
+		invalid: []string{
+			"",
+			"f81d4fae7dec11d0a76500a0c91e6bf6",
+			"f81d4fae-7dec-11d0-a765-00a0c91e6bf",
+			"f81d4fae-7dec-11d0-a765-00a0c91e6bf6a",
+			"g81d4fae-7dec-11d0-a765-00a0c91e6bf6",
+			"{f81d4fae-7dec-11d0-a765-00a0c91e6bf6}",
+		},
+	}.check(t, "UUID(0)", UUID(0))
+
+	patternTest{
+		valid: []string{
+			"550e8400-e29b-41d4-a716-446655440000",
+			"550E8400-E29B-41D4-B716-446655440000",
+			"9b2c8a1e-0000-4000-8000-000000000000",
+		},
+		invalid: []string{
+			"f81d4fae-7dec-11d0-a765-00a0c91e6bf6",
+			"550e8400-e29b-41d4-c716-446655440000",
+			"550e8400-e29b-41d4-7716-446655440000",
+			"00000000-0000-0000-0000-000000000000",
+		},
+	}.check(t, "UUID(4)", UUID(4))
+
+	patternTest{
+		valid:   []string{"f81d4fae-7dec-11d0-a765-00a0c91e6bf6"},
+		invalid: []string{"550e8400-e29b-41d4-a716-446655440000"},
+	}.check(t, "UUID(1)", UUID(1))
+
+	patternTest{
+		valid:   []string{"f81d4fae-7dec-c1d0-0765-00a0c91e6bf6", "f81d4fae-7dec-C1d0-f765-00a0c91e6bf6"},
+		invalid: []string{"f81d4fae-7dec-b1d0-a765-00a0c91e6bf6"},
+	}.check(t, "UUID(12)", UUID(12))
+}
+
+func TestSemVer(t *testing.T) {
+
+	patternTest{
+		valid: []string{
+			"0.0.0",
+			"1.2.3",
+			"10.20.30",
+			"1.0.0-alpha",
+			"1.0.0-alpha.1",
+			"1.0.0-0.3.7",
+			"1.0.0-x.7.z.92",
+			"1.0.0-x-y-z.--",
+			"1.0.0-0a.1",
+			"1.0.0+20130313144700",
+			"1.0.0-beta+exp.sha.5114f85",
+			"1.0.0+21AF26D3----117B344092BD",
+		},
+		invalid: []string{
+			"",
+			"1",
+			"1.2",
+			"1.2.3.4",
+			"v1.2.3",
+			"01.2.3",
+			"1.02.3",
+			"1.2.03",
+			"1.0.0-",
+			"1.0.0-01",
+			"1.0.0-alpha..1",
+			"1.0.0+",
+			"1.0.0+build+2",
+			"1.0.0-al_pha",
+		},
+	}.check(t, "SemVer()", SemVer())
+}
+
+func TestISO8601(t *testing.T) {
+
+	patternTest{
+		valid: []string{
+			"2013-07-24",
+			"2013-12-31",
+			"2013-07-24T18:30",
+			"2013-07-24T18:30:05",
+			"2013-07-24T23:59:60",
+			"2013-07-24T18:30:05.123",
+			"2013-07-24T18:30:05,5",
+			"2013-07-24T18:30:05Z",
+			"2013-07-24t18:30:05z",
+			"2013-07-24T18:30:05+02:00",
+			"2013-07-24T18:30-0530",
+			"2013-07-24T18:30:05.123456789-08",
+		},
+		invalid: []string{
+			"",
+			"2013-7-24",
+			"2013-07-4",
+			"13-07-24",
+			"2013-00-24",
+			"2013-13-01",
+			"2013-07-00",
+			"2013-07-32",
+			"2013/07/24",
+			"20130724",
+			"2013-07-24T",
+			"2013-07-24T24:00",
+			"2013-07-24T18:60",
+			"2013-07-24T18:30:61",
+			"2013-07-24T18:30:05.",
+			"2013-07-24 18:30",
+			"2013-07-24T18:30+2",
+		},
+	}.check(t, "ISO8601()", ISO8601())
+}
+
+func TestCompose(t *testing.T) {
+
+	// patterns have no capture group
+	v := verbalexpressions.New().
+		BeginNamedCapture("version").And(SemVer()).EndCapture().
+		Find(" released on ").
+		BeginNamedCapture("date").And(ISO8601()).EndCapture()
+	got := v.CaptureMap("1.2.0-rc.1 released on 2013-07-24T18:30Z")
+	if got["version"] != "1.2.0-rc.1" || got["date"] != "2013-07-24T18:30Z" {
+		t.Errorf("captures are %v", got)
+	}
+
+	// each call returns a new expression
+	if a, b := IPv4(), IPv4(); a == b {
+		t.Error("IPv4() returns the same expression")
+	}
+
+	// search with word boundaries
+	w := verbalexpressions.New().WordBoundary().And(IPv4()).WordBoundary()
+	if got := w.Captures("from 10.0.0.1 and 300.1.1.1 to 192.168.1.255"); len(got) != 2 || got[0][0] != "10.0.0.1" || got[1][0] != "192.168.1.255" {
+		t.Errorf("matches are %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+/* quantifiers apply to the previous step of the expression */
+
+// maxRepeat is the largest count of a repetition RE2 accepts
+const maxRepeat = 1000
+
+// validRepeat tells if a repetition from min to max times, max is -1 when
+// there is no upper bound, can be compiled
+func validRepeat(min, max int) bool {
+	return min >= 0 && min <= maxRepeat && (max == -1 || max >= min && max <= maxRepeat)
+}
+
+// repeatLast replaces the last node of the current sequence by a quantifier
+func (v *VerbalExpression) repeatLast(step string, min, max int, args ...interface{}) *VerbalExpression {
+	if !validRepeat(min, max) {
+		return v.fail(step, ErrInvalidRepeat, args...)
+	}
+	if v.current().last() == nil {
+		return v.fail(step, ErrNothingToRepeat, args...)
+	}
+	v = v.fork()
+	v.invalidate()
+	seq := v.current()
+	seq.nodes[len(seq.nodes)-1] = &quantifier{child: seq.last(), min: min, max: max}
+	return v
+}
+
+// OneOrMore repeats the previous step at least one time
+//
+//	// match "1", "42", "2013"...
+//	v.Any("0123456789").OneOrMore()
+func (v *VerbalExpression) OneOrMore() *VerbalExpression {
+	return v.repeatLast("OneOrMore", 1, -1)
+}
+
+// ZeroOrMore repeats the previous step any number of times, even zero
+func (v *VerbalExpression) ZeroOrMore() *VerbalExpression {
+	return v.repeatLast("ZeroOrMore", 0, -1)
+}
+
+// Optional makes the previous step optional
+func (v *VerbalExpression) Optional() *VerbalExpression {
+	return v.repeatLast("Optional", 0, 1)
+}
+
+// Times repeats the previous step exactly n times
+func (v *VerbalExpression) Times(n int) *VerbalExpression {
+	return v.repeatLast("Times", n, n, n)
+}
+
+// AtLeast repeats the previous step n times or more
+func (v *VerbalExpression) AtLeast(n int) *VerbalExpression {
+	return v.repeatLast("AtLeast", n, -1, n)
+}
+
+// Between repeats the previous step from min to max times, counts can't be
+// more than 1000, as for Times() and AtLeast()
+//
+//	// match 2 to 4 words separated by spaces
+//	v.BeginCapture().Word().Maybe(" ").EndCapture().Between(2, 4)
+func (v *VerbalExpression) Between(min, max int) *VerbalExpression {
+	return v.repeatLast("Between", min, max, min, max)
+}
+
+// Lazy makes the previous repetition match as few characters as possible,
+// instead of as many as possible. It works with quantifiers above, and with
+// steps that repeat something like Anything(), Something() or Multiple().
+//
+//	// in "<a><b>", match "<a>" instead of the whole string
+//	v.Find("<").Anything().Lazy().Find(">")
+func (v *VerbalExpression) Lazy() *VerbalExpression {
+	if _, ok := v.current().last().(*quantifier); !ok {
+		return v.fail("Lazy", ErrNotRepeated)
+	}
+	v = v.fork()
+	v.invalidate()
+	v.current().last().(*quantifier).lazy = true
+	return v
+}
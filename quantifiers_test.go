@@ -0,0 +1,93 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuantifiers(t *testing.T) {
+
+	tests := []struct {
+		v      *VerbalExpression
+		expect string
+	}{
+		{New().Any("0123456789").OneOrMore(), `(?m)[0123456789]+`},
+		{New().Find("ab").ZeroOrMore(), `(?m)(?:ab)*`},
+		{New().Find("a").Find("b").Optional(), `(?m)ab?`},
+		{New().Word().Times(3), `(?m)(?:\w+){3}`},
+		{New().Word().AtLeast(2), `(?m)(?:\w+){2,}`},
+		{New().Word().Between(2, 4), `(?m)(?:\w+){2,4}`},
+		{New().BeginCapture().Find("ab").EndCapture().OneOrMore(), `(?m)(ab)+`},
+		{New().BeginCapture().Find("a").OneOrMore().EndCapture(), `(?m)(a+)`},
+		{New().Find("<").Anything().Lazy().Find(">"), `(?m)<.*?>`},
+		{New().Any("ab").Between(1, 3).Lazy(), `(?m)[ab]{1,3}?`},
+		{New().StartOfLine().Optional(), `(?m)(?:^)?`},
+	}
+	for _, test := range tests {
+		assertStringEquals(test.v.String(), test.expect, t)
+		if _, err := test.v.Compile(); err != nil {
+			t.Errorf("%s doesn't compile: %v", test.v, err)
+		}
+	}
+}
+
+func TestQuantifiersMatch(t *testing.T) {
+
+	v := New().StartOfLine().Any("0123456789").Between(2, 4).EndOfLine()
+	for s, expect := range map[string]bool{"1": false, "12": true, "1234": true, "12345": false} {
+		if v.Test(s) != expect {
+			t.Errorf("%v matching %s should be %v", v.Regex(), s, expect)
+		}
+	}
+
+	v = New().Find("<").Anything().Lazy().Find(">")
+	assertStringEquals(v.Regex().FindString("<a><b>"), "<a>", t)
+}
+
+func TestQuantifiersErrors(t *testing.T) {
+
+	if err := New().OneOrMore().Err(); !errors.Is(err, ErrNothingToRepeat) {
+		t.Errorf("%v is not ErrNothingToRepeat", err)
+	}
+	if err := New().Find("a").BeginCapture().Times(2).Err(); !errors.Is(err, ErrNothingToRepeat) {
+		t.Errorf("%v is not ErrNothingToRepeat", err)
+	}
+	if err := New().Find("a").Between(3, 2).Err(); !errors.Is(err, ErrInvalidRepeat) {
+		t.Errorf("%v is not ErrInvalidRepeat", err)
+	}
+	if err := New().Find("a").Times(-1).Err(); !errors.Is(err, ErrInvalidRepeat) {
+		t.Errorf("%v is not ErrInvalidRepeat", err)
+	}
+	// more than RE2 accepts
+	for _, v := range []*VerbalExpression{
+		New().Find("a").Times(2000),
+		New().Find("a").AtLeast(1001),
+		New().Find("a").Between(1, 1001),
+		New().Multiple("a", 0, 1001),
+	} {
+		if !errors.Is(v.Err(), ErrInvalidRepeat) {
+			t.Errorf("%v is not ErrInvalidRepeat", v.Err())
+		}
+	}
+	assertStringEquals(New().Find("a").Times(2000).Err().Error(), `verbalexpressions: Times(2000): invalid min or max repetition`, t)
+	if err := New().Find("a").Times(1000).Err(); err != nil {
+		t.Errorf("Times(1000): %v", err)
+	}
+	if err := New().Find("a").Lazy().Err(); !errors.Is(err, ErrNotRepeated) {
+		t.Errorf("%v is not ErrNotRepeated", err)
+	}
+}
+
+func TestQuantifiersImmutable(t *testing.T) {
+
+	base := New().Immutable().Find("a").Anything()
+	lazy := base.Lazy()
+	more := base.OneOrMore()
+
+	assertStringEquals(base.String(), `(?m)a.*`, t)
+	assertStringEquals(lazy.String(), `(?m)a.*?`, t)
+	assertStringEquals(more.String(), `(?m)a(?:.*)+`, t)
+}
@@ -0,0 +1,65 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"regexp/syntax"
+	"strings"
+)
+
+// Raw adds a fragment of regular expression written by hand, when the
+// verbal methods are not enough. The fragment must be valid RE2 syntax on
+// its own: "a(b" is reported as an error, by Err(), instead of failing when
+// the expression is compiled.
+//
+//	// match "2013-07-01"
+//	v.Raw(`\d{4}-\d{2}-\d{2}`)
+//
+// Capture groups of the fragment are counted with other groups, and named
+// ones must not be used by another group of the expression. Flags set in
+// the fragment, like "(?i)", apply to the fragment only.
+func (v *VerbalExpression) Raw(fragment string) *VerbalExpression {
+	re, err := syntax.Parse(fragment, syntax.Perl)
+	if err != nil {
+		return v.fail("Raw", err, fragment)
+	}
+
+	names := []string{}
+	used := v.groupNames()
+	for _, name := range re.CapNames() {
+		if name == "" {
+			continue
+		}
+		for _, n := range used {
+			if n == name {
+				return v.fail("Raw", ErrDuplicateName, fragment)
+			}
+		}
+		names = append(names, name)
+	}
+
+	return v.add(&raw{
+		text:   fragment,
+		caps:   re.MaxCap(),
+		names:  names,
+		atomic: atomicSyntax(fragment, re),
+	})
+}
+
+// atomicSyntax tells if a parsed fragment can be repeated, or used with
+// other nodes, without being grouped
+func atomicSyntax(fragment string, re *syntax.Regexp) bool {
+	if strings.Contains(fragment, "(?") {
+		// flags may be set, they must be scoped to the fragment
+		return false
+	}
+	switch re.Op {
+	case syntax.OpCapture:
+		return true
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL, syntax.OpLiteral:
+		// "a|b" is parsed as a class, but it can't be repeated as is
+		return !strings.Contains(fragment, "|") && (re.Op != syntax.OpLiteral || len(re.Rune) == 1)
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"errors"
+	"regexp/syntax"
+	"testing"
+)
+
+func TestRaw(t *testing.T) {
+
+	tests := []struct {
+		v      *VerbalExpression
+		expect string
+	}{
+		{New().Raw(`\d{4}-\d{2}`), `(?m)(?:\d{4}-\d{2})`},
+		{New().Raw(`\d`).OneOrMore(), `(?m)\d+`},
+		{New().Raw(`[a-f]`).Find("x"), `(?m)[a-f]x`},
+		{New().Raw(`(a|b)`).Times(2), `(?m)(a|b){2}`},
+		{New().Find("x").Raw(`a|b`), `(?m)x(?:a|b)`},
+		{New().Raw(`(?i)a`).Find("b"), `(?m)(?:(?i)a)b`},
+	}
+	for _, test := range tests {
+		assertStringEquals(test.v.String(), test.expect, t)
+		if _, err := test.v.Compile(); err != nil {
+			t.Errorf("%s doesn't compile: %v", test.v, err)
+		}
+	}
+
+	v := New().Raw(`(?i)a`).Find("b")
+	if !v.Test("Ab") || v.Test("AB") {
+		t.Errorf("%v flags of the fragment should not apply to b", v.Regex())
+	}
+}
+
+func TestRawCaptures(t *testing.T) {
+
+	v := New().Raw(`(\d+)-(?P<month>\d+)`).Find("-").BeginCapture().Digit().OneOrMore().EndCapture()
+	c := v.Captures("2013-07-01")
+	if len(c) != 1 || len(c[0]) != 4 || c[0][3] != "01" {
+		t.Errorf("%v is not [[2013-07-01 2013 07 01]]", c)
+	}
+	assertStringEquals(v.CaptureMap("2013-07-01")["month"], "07", t)
+
+	if err := New().Raw(`(?P<a>x)`).BeginNamedCapture("a").Err(); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName", err)
+	}
+	if err := New().BeginNamedCapture("a").EndCapture().Raw(`(?P<a>x)`).Err(); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName", err)
+	}
+}
+
+func TestRawErrors(t *testing.T) {
+
+	v := New().Find("a").Raw("a(b")
+	var serr *syntax.Error
+	if !errors.As(v.Err(), &serr) || serr.Code != syntax.ErrMissingParen {
+		t.Errorf("%v is not a missing paren error", v.Err())
+	}
+	if err := v.Err().(*StepError); err.Step != "Raw" {
+		t.Errorf("%v is not reported for Raw", err)
+	}
+	if _, err := v.Compile(); err == nil {
+		t.Errorf("Compile() should fail")
+	}
+}
@@ -0,0 +1,540 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+/* expressions as lists of builder method calls, to print or rebuild them */
+
+// step is a call to a builder method. Args are strings, ints, bools and
+// runes, or sub steps: closure for a func(*VerbalExpression) argument, chain
+// for a *VerbalExpression and classChain for a *CharClass. Steps between
+// BeginCapture() and EndCapture() are in body.
+type step struct {
+	name string
+	args []interface{}
+	body []step
+}
+
+// closure is the body of a func(*VerbalExpression) argument
+type closure []step
+
+// chain is an expression built from New(), with flags set to flags
+type chain struct {
+	flags Flag
+	steps []step
+}
+
+// classChain is a class built from NewClass()
+type classChain []step
+
+// captures tells if s begins a capture group, ended after its body
+func (s step) captures() bool {
+	return strings.HasPrefix(s.name, "Begin")
+}
+
+var (
+	expressionType = reflect.TypeOf((*VerbalExpression)(nil))
+	classType      = reflect.TypeOf((*CharClass)(nil))
+	closureType    = reflect.TypeOf(func(*VerbalExpression) {})
+)
+
+// notSteps are methods returning a VerbalExpression or a CharClass that
+// are not steps of an expression
+var notSteps = map[string]bool{"Clone": true, "Immutable": true, "Mutable": true}
+
+// stepMethod returns the method "name" of receiver, expressionType or
+// classType, if it is a builder step
+func stepMethod(receiver reflect.Type, name string) (reflect.Method, bool) {
+	m, ok := receiver.MethodByName(name)
+	if !ok || notSteps[name] || m.Type.NumOut() != 1 || m.Type.Out(0) != receiver {
+		return m, false
+	}
+	return m, true
+}
+
+// takesArgs tells if method m can be called with n arguments
+func takesArgs(m reflect.Method, n int) bool {
+	// In(0) is the receiver
+	params := m.Type.NumIn() - 1
+	if m.Type.IsVariadic() {
+		return n >= params-1
+	}
+	return n == params
+}
+
+// paramType returns the type of argument i of method m
+func paramType(m reflect.Method, i int) reflect.Type {
+	params := m.Type.NumIn() - 1
+	if m.Type.IsVariadic() && i >= params-1 {
+		return m.Type.In(params).Elem()
+	}
+	return m.Type.In(1 + i)
+}
+
+// apply calls the builder methods of steps on v
+func (v *VerbalExpression) apply(steps []step) *VerbalExpression {
+	for _, s := range flatten(steps) {
+		v = call(v, s.name, s.args).(*VerbalExpression)
+	}
+	return v
+}
+
+// build returns the expression made by c
+func (c chain) build() *VerbalExpression {
+	return New().apply(append(flagSteps(c.flags), c.steps...))
+}
+
+// flatten returns steps with the body of capture groups and EndCapture()
+// after each BeginCapture()
+func flatten(steps []step) []step {
+	flat := []step{}
+	for _, s := range steps {
+		flat = append(flat, step{name: s.name, args: s.args})
+		if s.captures() {
+			flat = append(flat, flatten(s.body)...)
+			flat = append(flat, step{name: "EndCapture"})
+		}
+	}
+	return flat
+}
+
+// nest moves steps between BeginCapture() and EndCapture() into the body of
+// the capture step, as flatten() expects them. Rest are the steps after an
+// EndCapture() closing no group of steps, nil if there is none.
+func nest(steps []step) (nested, rest []step) {
+	nested = []step{}
+	for len(steps) > 0 {
+		s := steps[0]
+		steps = steps[1:]
+		if s.name == "EndCapture" {
+			return nested, steps
+		}
+		if s.captures() {
+			s.body, steps = nest(steps)
+		}
+		nested = append(nested, s)
+	}
+	return nested, nil
+}
+
+// call calls the method "name" of receiver, a *VerbalExpression or a
+// *CharClass, and returns its result. Sub steps in args are built first.
+func call(receiver interface{}, name string, args []interface{}) interface{} {
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case closure:
+			in[i] = reflect.ValueOf(func(g *VerbalExpression) { g.apply(arg) })
+		case chain:
+			in[i] = reflect.ValueOf(arg.build())
+		case classChain:
+			var c interface{} = NewClass()
+			for _, s := range arg {
+				c = call(c, s.name, s.args)
+			}
+			in[i] = reflect.ValueOf(c)
+		default:
+			in[i] = reflect.ValueOf(arg)
+		}
+	}
+	return reflect.ValueOf(receiver).MethodByName(name).Call(in)[0].Interface()
+}
+
+// flagSteps returns the steps setting flags f on New()
+func flagSteps(f Flag) []step {
+	return flagChanges(New().flags, f)
+}
+
+// flagChanges returns the steps changing flags from to f
+func flagChanges(from, f Flag) []step {
+	steps := []step{}
+	set := func(name string, flag Flag, arg bool) {
+		if (from^f)&flag != 0 {
+			steps = append(steps, step{name: name, args: []interface{}{arg}})
+		}
+	}
+	set("SearchOneLine", MULTILINE, f&MULTILINE == 0)
+	set("WithAnyCase", IGNORE_CASE, f&IGNORE_CASE != 0)
+	set("MatchAllWithDot", DOTALL, f&DOTALL != 0)
+	set("Ungreedy", UNGREEDY, f&UNGREEDY != 0)
+	set("StopAtFirst", GLOBAL, f&GLOBAL == 0)
+	return steps
+}
+
+// chain returns the builder calls making the expression. Steps that failed
+// are not part of it, groups that are not ended are ended.
+func (v *VerbalExpression) chain() chain {
+	d := &decompiler{flags: v.flags & regexFlags, base: v.flags & regexFlags}
+	steps := d.node(v.top())
+	for _, alt := range v.alternatives {
+		steps = append(steps, step{name: "Or", args: []interface{}{d.chain(alt)}})
+	}
+	return chain{flags: v.flags, steps: steps}
+}
+
+// decompiler finds the steps building an expression tree
+type decompiler struct {
+	flags Flag // flags active at the current position, as for renderer
+	base  Flag // flags of the expression the steps are called on
+}
+
+// same tells if nodes a and b are written the same way
+func (d *decompiler) same(a, b node) bool {
+	ra, rb := &renderer{flags: d.flags}, &renderer{flags: d.flags}
+	ra.render(a, alone)
+	rb.render(b, alone)
+	return ra.String() == rb.String()
+}
+
+// raw returns a Raw() step for nodes that no other step adds
+func (d *decompiler) raw(n node) step {
+	r := &renderer{flags: d.flags}
+	r.render(n, alone)
+	return step{name: "Raw", args: []interface{}{r.String()}}
+}
+
+var anchorSteps = map[anchorKind]string{
+	startOfLine:     "StartOfLine",
+	endOfLine:       "EndOfLine",
+	startOfInput:    "StartOfInput",
+	endOfInput:      "EndOfInput",
+	wordBoundary:    "WordBoundary",
+	notWordBoundary: "NotWordBoundary",
+}
+
+// node returns the steps adding n
+func (d *decompiler) node(n node) []step {
+	switch n := n.(type) {
+	case *literal:
+		return []step{{name: "Find", args: []interface{}{n.text}}}
+	case *class:
+		return []step{d.class(n)}
+	case *anchor:
+		return []step{{name: anchorSteps[n.kind]}}
+	case *sequence:
+		steps := []step{}
+		for _, child := range n.nodes {
+			steps = append(steps, d.node(child)...)
+		}
+		return steps
+	case *alternation:
+		return []step{d.alternation(n)}
+	case *group:
+		return d.group(n)
+	case *quantifier:
+		return d.quantifier(n)
+	case *raw:
+		return []step{{name: "Raw", args: []interface{}{n.text}}}
+	}
+	// a single anyChar, no step matches exactly one character
+	return []step{d.raw(n)}
+}
+
+// chain returns the steps of an expression built apart, nested by Either(),
+// Or() or And()
+func (d *decompiler) chain(n node) chain {
+	if g, ok := n.(*group); ok && !g.capture && embedded(g.on, g.off) {
+		return d.embedded(g.on, g.off, g.body)
+	}
+	return d.embedded(0, 0, n)
+}
+
+// embedded tells if a group setting flags on and off can be made by
+// embed(), from flags changed on New()
+func embedded(on, off Flag) bool {
+	return on&defaultFlags == 0 && off&^defaultFlags == 0
+}
+
+// embedded returns the chain of n, nested by embed() in a group setting
+// flags on and off
+func (d *decompiler) embedded(on, off Flag, n node) chain {
+	flags := (defaultFlags | on) &^ off
+	sub := &decompiler{flags: (d.flags | on) &^ off, base: flags}
+	return chain{flags: flags | GLOBAL, steps: sub.node(n)}
+}
+
+// class returns the step adding class c
+func (d *decompiler) class(c *class) step {
+	steps, ok := classSteps(c.items)
+	switch {
+	case !ok:
+		return d.raw(c)
+	case c.negate:
+		steps = append(steps, step{name: "Not"})
+	case len(steps) == 1 && steps[0].name == "Chars":
+		return step{name: "Any", args: steps[0].args}
+	case len(steps) == 1 && steps[0].name != "Range":
+		// Digit(), Letter()... exist for both
+		return steps[0]
+	case onlyRanges(steps):
+		bounds := []interface{}{}
+		for _, s := range steps {
+			bounds = append(bounds, string(s.args[0].(rune)), string(s.args[1].(rune)))
+		}
+		return step{name: "Range", args: bounds}
+	}
+	return step{name: "Class", args: []interface{}{classChain(steps)}}
+}
+
+// onlyRanges tells if steps are all Range() calls
+func onlyRanges(steps []step) bool {
+	for _, s := range steps {
+		if s.name != "Range" {
+			return false
+		}
+	}
+	return true
+}
+
+// classSteps returns CharClass steps adding items, false if an item can't
+// be added by a step
+func classSteps(items []classItem) ([]step, bool) {
+	steps := []step{}
+	for len(items) > 0 {
+		s, n := step{}, 1
+		switch item := items[0]; {
+		case hasItems(items, letterItems):
+			s.name, n = "Letter", len(letterItems)
+		case hasItems(items, hexDigitItems):
+			s.name, n = "HexDigit", len(hexDigitItems)
+		case item.name == `\d`:
+			s.name = "Digit"
+		case item.name == `\s`:
+			s.name = "Whitespace"
+		case item.name == `[:punct:]`:
+			s.name = "Punctuation"
+		case item.name == `\p{L}`:
+			s.name = "UnicodeLetter"
+		case strings.HasPrefix(item.name, `\p{`):
+			name := strings.TrimSuffix(strings.TrimPrefix(item.name, `\p{`), "}")
+			s.name, s.args = "UnicodeCategory", []interface{}{name}
+			if _, ok := unicode.Scripts[name]; ok {
+				s.name = "UnicodeScript"
+			}
+		case item.name != "":
+			return nil, false
+		case item.lo == item.hi:
+			// following characters are added at once
+			for n < len(items) && items[n].name == "" && items[n].lo == items[n].hi {
+				n++
+			}
+			runes := []rune{}
+			for _, i := range items[:n] {
+				runes = append(runes, i.lo)
+			}
+			s.name, s.args = "Chars", []interface{}{string(runes)}
+		default:
+			s.name, s.args = "Range", []interface{}{item.lo, item.hi}
+		}
+		steps = append(steps, s)
+		items = items[n:]
+	}
+	return steps, true
+}
+
+// hasItems tells if items start with prefix
+func hasItems(items, prefix []classItem) bool {
+	if len(items) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if items[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// alternation returns the step adding a
+func (d *decompiler) alternation(a *alternation) step {
+	if d.same(a, lineBreakNode()) {
+		return step{name: "LineBreak"}
+	}
+
+	literals := []interface{}{}
+	for _, alt := range a.alts {
+		if l, ok := alt.(*literal); ok {
+			literals = append(literals, l.text)
+		}
+	}
+	if len(literals) > 0 && len(literals) == len(a.alts) {
+		return step{name: "OneOf", args: literals}
+	}
+
+	chains := make([]interface{}, len(a.alts))
+	for i, alt := range a.alts {
+		chains[i] = d.chain(alt)
+	}
+	return step{name: "Either", args: chains}
+}
+
+// group returns the steps adding g, flags are set by the scoped steps, or
+// by And() for other combinations it can make
+func (d *decompiler) group(g *group) []step {
+	on, off := g.on&^d.flags, g.off&d.flags
+	if on == 0 && off == 0 {
+		if g.capture {
+			s := step{name: "BeginCapture", body: d.node(g.body)}
+			if g.name != "" {
+				s.name, s.args = "BeginNamedCapture", []interface{}{g.name}
+			}
+			return []step{s}
+		}
+		if seq, ok := g.body.(*sequence); ok && len(seq.nodes) == 3 {
+			if l, ok := seq.nodes[1].(*literal); ok && d.same(g, wholeWordNode(l.text)) {
+				return []step{{name: "WholeWord", args: []interface{}{l.text}}}
+			}
+		}
+		// no parenthesis are written
+		return d.node(g.body)
+	}
+
+	sub := &decompiler{flags: (d.flags | on) &^ off, base: d.base}
+	inner := &group{capture: g.capture, name: g.name, body: g.body}
+	scoped := func(name string) []step {
+		return []step{{name: name, args: []interface{}{closure(sub.node(inner))}}}
+	}
+	switch {
+	case on == IGNORE_CASE && off == 0:
+		if l, ok := g.body.(*literal); ok && !g.capture {
+			return []step{{name: "FindIgnoreCase", args: []interface{}{l.text}}}
+		}
+		return scoped("CaseInsensitive")
+	case on == 0 && off == IGNORE_CASE:
+		return scoped("CaseSensitive")
+	case on == DOTALL && off == 0:
+		return scoped("DotMatchesNewline")
+	}
+	if embedded(on, off) {
+		return []step{{name: "And", args: []interface{}{d.embedded(on, off, inner)}}}
+	}
+	// Group() scopes the flags changed from the expression
+	flags := (d.base | on) &^ off
+	if (d.flags|flags&^d.base)&^(d.base&^flags) == sub.flags {
+		sub.base = flags
+		steps := append(flagChanges(d.base, flags), sub.node(inner)...)
+		return []step{{name: "Group", args: []interface{}{closure(steps)}}}
+	}
+	return []step{d.raw(g)}
+}
+
+// quantifier returns the steps adding q
+func (d *decompiler) quantifier(q *quantifier) []step {
+	var s step
+	switch child := q.child.(type) {
+	case *anyChar:
+		switch {
+		case q.min == 0 && q.max == -1 && q.lazy:
+			return []step{{name: "AnythingLazy"}}
+		case q.min == 0 && q.max == -1:
+			s.name = "Anything"
+		case q.min == 1 && q.max == -1 && q.lazy:
+			return []step{{name: "SomethingLazy"}}
+		case q.min == 1 && q.max == -1:
+			s.name = "Something"
+		}
+	case *class:
+		chars, ok := classChars(child.items)
+		switch {
+		case child.negate && ok && q.min == 0 && q.max == -1:
+			s.name, s.args = "AnythingBut", []interface{}{chars}
+		case child.negate && ok && q.min == 1 && q.max == -1:
+			s.name, s.args = "SomethingBut", []interface{}{chars}
+		case !child.negate && hasItems(child.items, wordItems) && len(child.items) == 1 && q.min == 1 && q.max == -1:
+			s.name = "Word"
+		case !child.negate && hasItems(child.items, unicodeWordItems) && len(child.items) == len(unicodeWordItems) && q.min == 1 && q.max == -1:
+			s.name = "UnicodeWord"
+		}
+	case *literal:
+		switch {
+		case q.min == 0 && q.max == 1:
+			s.name, s.args = "Maybe", []interface{}{child.text}
+		case child.text == "\t" && q.min == 1 && q.max == -1:
+			s.name = "Tab"
+		case q.min == 1 && q.max == -1:
+			s.name, s.args = "Multiple", []interface{}{child.text}
+		case q.max == -1:
+			s.name, s.args = "Multiple", []interface{}{child.text, q.min}
+		default:
+			s.name, s.args = "Multiple", []interface{}{child.text, q.min, q.max}
+		}
+	case *alternation:
+		if value, ok := notValue(child); ok && d.same(q, notNode(value)) {
+			return []step{{name: "Not", args: []interface{}{value}}}
+		}
+	}
+
+	steps := []step{s}
+	if s.name == "" {
+		steps = d.node(q.child)
+		if len(steps) != 1 {
+			steps = []step{{name: "Group", args: []interface{}{closure(steps)}}}
+		}
+		steps = append(steps, repeatStep(q.min, q.max))
+	}
+	if q.lazy {
+		steps = append(steps, step{name: "Lazy"})
+	}
+	return steps
+}
+
+// classChars returns characters of items if they are all single characters
+func classChars(items []classItem) (string, bool) {
+	runes := []rune{}
+	for _, item := range items {
+		if item.name != "" || item.lo != item.hi {
+			return "", false
+		}
+		runes = append(runes, item.lo)
+	}
+	return string(runes), true
+}
+
+// notValue returns the value given to Not() if a was built by it, the
+// caller must check it with notNode()
+func notValue(a *alternation) (string, bool) {
+	if len(a.alts) == 0 {
+		// Not("") has no alternatives, Either() can't make it
+		return "", true
+	}
+	seq, ok := a.alts[len(a.alts)-1].(*sequence)
+	if !ok {
+		return "", false
+	}
+	value := ""
+	for _, n := range seq.nodes {
+		switch n := n.(type) {
+		case *literal:
+			value += n.text
+		case *class:
+			if len(n.items) != 1 {
+				return "", false
+			}
+			value += string(n.items[0].lo)
+		}
+	}
+	return value, value != ""
+}
+
+// repeatStep returns the quantifier step repeating from min to max times
+func repeatStep(min, max int) step {
+	switch {
+	case min == 0 && max == 1:
+		return step{name: "Optional"}
+	case min == 0 && max == -1:
+		return step{name: "ZeroOrMore"}
+	case min == 1 && max == -1:
+		return step{name: "OneOrMore"}
+	case max == -1:
+		return step{name: "AtLeast", args: []interface{}{min}}
+	case min == max:
+		return step{name: "Times", args: []interface{}{min}}
+	}
+	return step{name: "Between", args: []interface{}{min, max}}
+}
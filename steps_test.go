@@ -0,0 +1,47 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import "testing"
+
+func TestStepsRebuildExpression(t *testing.T) {
+
+	tests := []*VerbalExpression{
+		New(),
+		New().StartOfLine().Find("http").Maybe("s").Find("://").Word().EndOfLine(),
+		New().SearchOneLine(true).WithAnyCase(true).MatchAllWithDot(true).Ungreedy(true).StopAtFirst(true).Anything(),
+		New().AnythingBut("ab").SomethingBut("c").Lazy().AnythingLazy().SomethingLazy().Something(),
+		New().Not("abc").LineBreak().Tab().WholeWord("cat").Multiple("x", 2, 5).Multiple("y", 3).Multiple("z"),
+		New().Find("a").Not("").Find("b"),
+		New().Any("xyz").Range("a", "f", 0, 9).Digit().Whitespace().Letter().HexDigit().Punctuation(),
+		New().Class(NewClass().Letter().Digit().Chars("_-").Range('α', 'ω').Not()),
+		New().UnicodeLetter().UnicodeScript("Han").UnicodeCategory("Lu").UnicodeWord(),
+		New().StartOfInput().WordBoundary().NotWordBoundary().EndOfInput(),
+		New().BeginCapture().Find("a").BeginNamedCapture("b").Digit().EndCapture().EndCapture().Optional(),
+		New().Find("a").OneOrMore().Digit().ZeroOrMore().Letter().Times(2).Word().AtLeast(2).Find("b").Between(1, 3).Lazy(),
+		New().Group(func(g *VerbalExpression) { g.Find("a").Digit() }).OneOrMore(),
+		New().Capture(func(g *VerbalExpression) { g.Find("a").WithAnyCase(true) }),
+		New().CaseInsensitive(func(g *VerbalExpression) {
+			g.Find("a").CaseSensitive(func(g *VerbalExpression) { g.Find("b") }).DotMatchesNewline(func(g *VerbalExpression) {
+				g.Anything()
+			})
+		}).FindIgnoreCase("c"),
+		New().Either(New().Find("a"), New().SearchOneLine(true).StartOfLine(), New().Find("b").Or(New().Find("c"))).OneOf("d", "e"),
+		New().Find("a").And(New().WithAnyCase(true).Find("b")).Or(New().MatchAllWithDot(true).Anything()),
+		New().Find("x").And(New().Find("a")).Either(New().Find("b"), New().Find("c")).WithAnyCase(true),
+		New().SearchOneLine(true).Ungreedy(true).Group(func(g *VerbalExpression) {
+			g.SearchOneLine(false).Ungreedy(false).StartOfLine().Word().Lazy()
+		}),
+		New().Raw(`(\d)+`).Raw(`(?i)x`).Optional(),
+		New().BeginCapture().Find("not ended"),
+		New().Find("a").Range("a"),
+	}
+	for _, v := range tests {
+		r := v.chain().build()
+		assertStringEquals(r.String(), v.String(), t)
+		if r.Err() != nil {
+			t.Errorf("%s: %v", v.GoSource(), r.Err())
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import "unicode"
+
+/* unicode classes, Word() and Letter() only match ASCII characters */
+
+// unicodeWordItems are characters of words in any language: letters, marks
+// (accents), digits and connectors like "_"
+var unicodeWordItems = []classItem{{name: `\p{L}`}, {name: `\p{M}`}, {name: `\p{Nd}`}, {name: `\p{Pc}`}}
+
+// unicodeItem returns the class item for a script or category name. The
+// name must exist in the tables of the unicode package.
+func unicodeItem(name string, tables map[string]*unicode.RangeTable) ([]classItem, error) {
+	if _, ok := tables[name]; !ok {
+		return nil, ErrUnknownUnicodeClass
+	}
+	return []classItem{{name: `\p{` + name + `}`}}, nil
+}
+
+// UnicodeLetter adds letters of any language
+func (c *CharClass) UnicodeLetter() *CharClass {
+	return c.add([]classItem{{name: `\p{L}`}})
+}
+
+// UnicodeScript adds characters of a script such as "Latin", "Cyrillic" or
+// "Han", see unicode.Scripts for names
+func (c *CharClass) UnicodeScript(name string) *CharClass {
+	items, err := unicodeItem(name, unicode.Scripts)
+	if err != nil {
+		if c.err == nil {
+			c.err = &StepError{Step: "UnicodeScript", Args: []interface{}{name}, Err: err}
+		}
+		return c
+	}
+	return c.add(items)
+}
+
+// UnicodeCategory adds characters of a category such as "Lu" (upper case
+// letters) or "Nd" (decimal digits), see unicode.Categories for names
+func (c *CharClass) UnicodeCategory(name string) *CharClass {
+	items, err := unicodeItem(name, unicode.Categories)
+	if err != nil {
+		if c.err == nil {
+			c.err = &StepError{Step: "UnicodeCategory", Args: []interface{}{name}, Err: err}
+		}
+		return c
+	}
+	return c.add(items)
+}
+
+// UnicodeLetter matches one letter of any language
+func (v *VerbalExpression) UnicodeLetter() *VerbalExpression {
+	return v.add(&class{items: []classItem{{name: `\p{L}`}}})
+}
+
+// UnicodeScript matches one character of a script such as "Latin",
+// "Cyrillic" or "Han", see unicode.Scripts for names
+func (v *VerbalExpression) UnicodeScript(name string) *VerbalExpression {
+	items, err := unicodeItem(name, unicode.Scripts)
+	if err != nil {
+		return v.fail("UnicodeScript", err, name)
+	}
+	return v.add(&class{items: items})
+}
+
+// UnicodeCategory matches one character of a category such as "Lu" (upper
+// case letters) or "Nd" (decimal digits), see unicode.Categories for names
+func (v *VerbalExpression) UnicodeCategory(name string) *VerbalExpression {
+	items, err := unicodeItem(name, unicode.Categories)
+	if err != nil {
+		return v.fail("UnicodeCategory", err, name)
+	}
+	return v.add(&class{items: items})
+}
+
+// UnicodeWord matches any word, as Word() does, but in any language:
+// "Ёжик", "東京" or "café"
+func (v *VerbalExpression) UnicodeWord() *VerbalExpression {
+	return v.add(&quantifier{child: &class{items: unicodeWordItems}, min: 1, max: -1})
+}
@@ -0,0 +1,61 @@
+// Copyright 2013 Patrice FERLET
+// Use of this source code is governed by MIT-style
+// license that can be found in the LICENSE file
+package verbalexpressions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnicodeWord(t *testing.T) {
+
+	v := New().StartOfLine().UnicodeWord().EndOfLine()
+	assertStringEquals(v.String(), `(?m)^[\p{L}\p{M}\p{Nd}\p{Pc}]+$`, t)
+	for _, s := range []string{"Ёжик", "東京", "café", "snake_case", "aé"} {
+		if !v.Test(s) {
+			t.Errorf("%v should match %s", v.Regex(), s)
+		}
+	}
+	if v.Test("two words") {
+		t.Errorf("%v should not match \"two words\"", v.Regex())
+	}
+
+	// Word() is ASCII only
+	if New().StartOfLine().Word().EndOfLine().Test("café") {
+		t.Errorf("Word() should not match café")
+	}
+}
+
+func TestUnicodeClasses(t *testing.T) {
+
+	v := New().UnicodeScript("Han").OneOrMore()
+	assertStringEquals(v.String(), `(?m)\p{Han}+`, t)
+	assertStringEquals(v.Regex().FindString("Tokyo 東京"), "東京", t)
+
+	v = New().UnicodeCategory("Lu").UnicodeLetter()
+	assertStringEquals(v.String(), `(?m)\p{Lu}\p{L}`, t)
+	if !v.Test("Éa") || v.Test("éa") {
+		t.Errorf("%v should match Éa and not éa", v.Regex())
+	}
+
+	c := NewClass().UnicodeScript("Cyrillic").UnicodeCategory("Nd").UnicodeLetter()
+	v = New().Class(c)
+	assertStringEquals(v.String(), `(?m)[\p{Cyrillic}\p{Nd}\p{L}]`, t)
+}
+
+func TestUnknownUnicodeClass(t *testing.T) {
+
+	if err := New().UnicodeScript("Klingon").Err(); !errors.Is(err, ErrUnknownUnicodeClass) {
+		t.Errorf("%v is not ErrUnknownUnicodeClass", err)
+	}
+	if err := New().UnicodeCategory("Xx").Err(); !errors.Is(err, ErrUnknownUnicodeClass) {
+		t.Errorf("%v is not ErrUnknownUnicodeClass", err)
+	}
+	if err := New().Class(NewClass().UnicodeScript("Latn")).Err(); !errors.Is(err, ErrUnknownUnicodeClass) {
+		t.Errorf("%v is not ErrUnknownUnicodeClass", err)
+	}
+	if err := New().Class(NewClass().UnicodeCategory("")).Err(); !errors.Is(err, ErrUnknownUnicodeClass) {
+		t.Errorf("%v is not ErrUnknownUnicodeClass", err)
+	}
+}
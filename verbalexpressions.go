@@ -5,10 +5,10 @@ package verbalexpressions
 
 import (
 	"fmt"
-	"log"
 	"regexp"
 	"strconv"
-	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 type Flag uint
@@ -21,15 +21,18 @@ const (
 	GLOBAL
 )
 
-// VerbalExpression structure to create expression
+// VerbalExpression structure to create expression. The zero value is an
+// empty expression without flags, New() sets the multiline and global modes.
 type VerbalExpression struct {
-	expression string
-	parts      []string
-	suffixes   string
-	prefixes   string
-	flags      Flag
-	compiled   bool
-	regexp     *regexp.Regexp
+	root         *sequence // nil for the zero value
+	open         []*sequence // bodies of groups that are not ended yet
+	alternatives []node      // expressions chained with Or
+	flags        Flag
+	err          error
+	immutable    bool
+
+	mu     sync.Mutex // guards regexp, set on first compilation
+	regexp *regexp.Regexp
 }
 
 // quote is an alias to regexp.QuoteMeta
@@ -38,7 +41,7 @@ func quote(s string) string {
 }
 
 // utility function to return only strings
-func tostring(i interface{}) string {
+func tostring(i interface{}) (string, error) {
 	var r string
 	switch x := i.(type) {
 	case string:
@@ -52,9 +55,9 @@ func tostring(i interface{}) string {
 	case int:
 		r = strconv.FormatInt(int64(x), 10)
 	default:
-		log.Panicf("Could not convert %v %t", x, x)
+		return "", fmt.Errorf("%w: could not convert %v (%T)", ErrUnsupportedType, x, x)
 	}
-	return r
+	return r, nil
 }
 
 // Instanciate a new VerbalExpression. You should use this method to
@@ -65,103 +68,328 @@ func tostring(i interface{}) string {
 func New() *VerbalExpression {
 	r := new(VerbalExpression)
 	r.flags = MULTILINE | GLOBAL
-	r.parts = make([]string, 0)
+	r.root = &sequence{}
 	return r
 }
 
+// Clone returns a deep copy of the expression. Modifying the copy doesn't
+// change the original one, and vice versa. The copy is immutable if the
+// original is.
+func (v *VerbalExpression) Clone() *VerbalExpression {
+	c := &VerbalExpression{
+		root:      copyNode(v.top()).(*sequence),
+		flags:     v.flags,
+		err:       v.err,
+		immutable: v.immutable,
+	}
+	for _, alt := range v.alternatives {
+		c.alternatives = append(c.alternatives, copyNode(alt))
+	}
+	// open groups are always the last node of their parent sequence
+	parent := c.root
+	for range v.open {
+		parent = parent.last().(*group).body.(*sequence)
+		c.open = append(c.open, parent)
+	}
+	return c
+}
+
+// Immutable returns a copy of the expression where each builder method
+// returns a new VerbalExpression, leaving the receiver untouched. This way, a
+// partial expression can be shared and extended in several ways:
+//
+//	url := verbalexpressions.New().Immutable().StartOfLine().Find("http")
+//	secure := url.Find("s://")
+//	any := url.Maybe("s").Find("://")
+func (v *VerbalExpression) Immutable() *VerbalExpression {
+	c := v.Clone()
+	c.immutable = true
+	return c
+}
+
+// Mutable returns a copy of the expression where builder methods modify and
+// return the receiver, this is the default for New().
+func (v *VerbalExpression) Mutable() *VerbalExpression {
+	c := v.Clone()
+	c.immutable = false
+	return c
+}
+
+// fork returns the VerbalExpression to modify: the receiver, or a copy of
+// it if the expression is immutable
+func (v *VerbalExpression) fork() *VerbalExpression {
+	if v.immutable {
+		return v.Clone()
+	}
+	return v
+}
+
 // append a modifier
 func (v *VerbalExpression) addmodifier(f Flag) *VerbalExpression {
-	v.compiled = false //reinit previous regexp compilation
+	v = v.fork()
+	v.invalidate()
 	v.flags |= f
 	return v
 }
 
 // remove a modifier
 func (v *VerbalExpression) removemodifier(f Flag) *VerbalExpression {
-	v.compiled = false //reinit previous regexp compilation
+	v = v.fork()
+	v.invalidate()
 	v.flags &= ^f
 	return v
 }
 
+// invalidate drops the compiled regexp, the expression is modified
+func (v *VerbalExpression) invalidate() {
+	v.mu.Lock()
+	v.regexp = nil
+	v.mu.Unlock()
+}
+
 // append modifiers that are activated
 func (v *VerbalExpression) getFlags() string {
-	flags := "misU" // warning, follow Flag const order
-	result := []rune{}
+	return flagString(v.flags)
+}
 
-	for i, flag := range flags {
-		if v.flags&(1<<uint(i)) != 0 {
-			result = append(result, flag)
-		}
+// current returns the sequence where nodes are appended: the body of the
+// last group that is not ended, or the root of the expression
+func (v *VerbalExpression) current() *sequence {
+	if len(v.open) > 0 {
+		return v.open[len(v.open)-1]
 	}
+	if v.root == nil {
+		v.root = &sequence{}
+	}
+	return v.root
+}
 
-	return string(result)
+// top returns the root sequence, without setting it for the zero value
+func (v *VerbalExpression) top() *sequence {
+	if v.root == nil {
+		return &sequence{}
+	}
+	return v.root
 }
 
-// add method, append expresions to the internal string that will be parsed
-func (v *VerbalExpression) add(s string) *VerbalExpression {
-	v.compiled = false //reinit previous regexp compilation
-	v.expression += s
+// add method, append a node to the expression tree that will be rendered
+func (v *VerbalExpression) add(n node) *VerbalExpression {
+	v = v.fork()
+	v.invalidate()
+	v.current().add(n)
 	return v
 }
 
+// begin appends a group and makes its body the current sequence until end()
+func (v *VerbalExpression) begin(g *group) *VerbalExpression {
+	v = v.fork()
+	v.invalidate()
+	body := &sequence{}
+	g.body = body
+	v.current().add(g)
+	v.open = append(v.open, body)
+	return v
+}
+
+// end closes the last group opened with begin()
+func (v *VerbalExpression) end(step string) *VerbalExpression {
+	if len(v.open) == 0 {
+		return v.fail(step, ErrNoOpenGroup)
+	}
+	v = v.fork()
+	v.invalidate()
+	v.open = v.open[:len(v.open)-1]
+	return v
+}
+
+// body returns the expression without its flags: the root sequence, or an
+// alternation of the root sequence and expressions chained with Or
+func (v *VerbalExpression) body() node {
+	if len(v.alternatives) == 0 {
+		return v.top()
+	}
+	alts := append([]node{v.top()}, v.alternatives...)
+	return &alternation{alts: alts}
+}
+
+// defaultFlags are the flags of New() that apply to the regular expression
+const defaultFlags = MULTILINE
+
+// embed returns a copy of the expression to be nested in another one. Flags
+// it changed from New() apply to the copy only, others are the flags of the
+// other expression, as for Group().
+func (v *VerbalExpression) embed() node {
+	return &group{
+		on:   v.flags & regexFlags &^ defaultFlags,
+		off:  defaultFlags &^ v.flags,
+		body: copyNode(v.body()),
+	}
+}
+
+// String returns the regular expression as RE2 syntax
+func (v *VerbalExpression) String() string {
+	r := &renderer{}
+	r.render(&setFlags{v.flags}, alone)
+	r.render(v.body(), alone)
+	return r.String()
+}
+
 // Start to capture something, stop with EndCapture()
 func (v *VerbalExpression) BeginCapture() *VerbalExpression {
-	v.suffixes += ")"
-	return v.add("(")
+	return v.begin(&group{capture: true})
+}
+
+// BeginNamedCapture starts to capture something in a group named "name",
+// stop with EndCapture(). Name must be made of letters, digits and
+// underscores, and must not be used by another group of the expression.
+func (v *VerbalExpression) BeginNamedCapture(name string) *VerbalExpression {
+	if !validName.MatchString(name) {
+		return v.fail("BeginNamedCapture", ErrInvalidName, name)
+	}
+	for _, n := range v.groupNames() {
+		if n == name {
+			return v.fail("BeginNamedCapture", ErrDuplicateName, name)
+		}
+	}
+	return v.begin(&group{capture: true, name: name})
+}
+
+// validName matches names allowed for capture groups
+var validName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// groupNames returns names of capture groups used in the expression
+func (v *VerbalExpression) groupNames() []string {
+	names := []string{}
+	walk(v.body(), func(n node) {
+		switch n := n.(type) {
+		case *group:
+			if n.name != "" {
+				names = append(names, n.name)
+			}
+		case *raw:
+			names = append(names, n.names...)
+		}
+	})
+	return names
+}
+
+// duplicateName returns a name of capture group used in several of exprs,
+// if any
+func duplicateName(exprs ...*VerbalExpression) (string, bool) {
+	seen := map[string]bool{}
+	for _, e := range exprs {
+		for _, name := range e.groupNames() {
+			if seen[name] {
+				return name, true
+			}
+			seen[name] = true
+		}
+	}
+	return "", false
 }
 
 // Stop capturing expresions parts
 func (v *VerbalExpression) EndCapture() *VerbalExpression {
-	v.suffixes = strings.Replace(v.suffixes, ")", "", 1)
-	return v.add(")")
+	return v.end("EndCapture")
 }
 
 // Anything will match any char
 func (v *VerbalExpression) Anything() *VerbalExpression {
-	return v.add(`(?:.*)`)
+	return v.add(&quantifier{child: &anyChar{}, min: 0, max: -1})
 }
 
 // AnythingBut will match anything excpeting the given string.
 func (v *VerbalExpression) AnythingBut(s string) *VerbalExpression {
-	return v.add(`(?:[^` + quote(s) + `]*)`)
+	if s == "" {
+		return v.fail("AnythingBut", ErrEmptyClass, s)
+	}
+	return v.add(&quantifier{child: &class{negate: true, items: chars(s)}, min: 0, max: -1})
 }
 
 // Something matches at least one char
 func (v *VerbalExpression) Something() *VerbalExpression {
-	return v.add(`(?:.+)`)
+	return v.add(&quantifier{child: &anyChar{}, min: 1, max: -1})
 }
 
 // Same as Something but excepting chars given in string "s"
 func (v *VerbalExpression) SomethingBut(s string) *VerbalExpression {
-	return v.add(`(?:[^` + quote(s) + `]+)`)
+	if s == "" {
+		return v.fail("SomethingBut", ErrEmptyClass, s)
+	}
+	return v.add(&quantifier{child: &class{negate: true, items: chars(s)}, min: 1, max: -1})
+}
+
+// AnythingLazy will match any char, as few as possible. It is usefull to
+// get the shortest text between delimiters:
+//
+//	// in "<a><b>", match "<a>" and "<b>" instead of the whole string
+//	v.Find("<").AnythingLazy().Find(">")
+func (v *VerbalExpression) AnythingLazy() *VerbalExpression {
+	return v.add(&quantifier{child: &anyChar{}, min: 0, max: -1, lazy: true})
+}
+
+// SomethingLazy matches at least one char, as few as possible
+func (v *VerbalExpression) SomethingLazy() *VerbalExpression {
+	return v.add(&quantifier{child: &anyChar{}, min: 1, max: -1, lazy: true})
 }
 
 // EndOfLine tells verbalexpressions to match a end of line.
 // Warning, to check multiple line, you must use SearchOneLine(true)
 func (v *VerbalExpression) EndOfLine() *VerbalExpression {
-	if !strings.HasSuffix(v.suffixes, "$") {
-		v.suffixes += "$"
-	}
-	return v
+	return v.add(&anchor{endOfLine})
 }
 
 // Maybe will search string zero on more times
 func (v *VerbalExpression) Maybe(s string) *VerbalExpression {
-	return v.add(`(?:` + quote(s) + `)?`)
+	return v.add(&quantifier{child: &literal{s}, min: 0, max: 1})
 }
 
 // StartOfLine seeks the begining of a line. As EndOfLine you should use
 // SearchOneLine(true) to test multiple lines
 func (v *VerbalExpression) StartOfLine() *VerbalExpression {
-	if !strings.HasPrefix(v.prefixes, "^") {
-		v.prefixes = `^` + v.prefixes
-	}
-	return v
+	return v.add(&anchor{startOfLine})
+}
+
+// StartOfInput matches the begining of the tested string, even in
+// multiline mode
+func (v *VerbalExpression) StartOfInput() *VerbalExpression {
+	return v.add(&anchor{startOfInput})
+}
+
+// EndOfInput matches the end of the tested string, even in multiline mode
+func (v *VerbalExpression) EndOfInput() *VerbalExpression {
+	return v.add(&anchor{endOfInput})
+}
+
+// WordBoundary matches between a word character (see Word()) and a non
+// word character, or the begining or end of the string
+func (v *VerbalExpression) WordBoundary() *VerbalExpression {
+	return v.add(&anchor{wordBoundary})
+}
+
+// NotWordBoundary matches where WordBoundary doesn't
+func (v *VerbalExpression) NotWordBoundary() *VerbalExpression {
+	return v.add(&anchor{notWordBoundary})
+}
+
+// WholeWord seeks string "s" as a whole word: it doesn't match inside a
+// larger word. Find("cat") matches "concatenate", WholeWord("cat") doesn't.
+func (v *VerbalExpression) WholeWord(s string) *VerbalExpression {
+	return v.add(wholeWordNode(s))
+}
+
+// wholeWordNode returns the tree matched by WholeWord(s)
+func wholeWordNode(s string) node {
+	return &group{body: &sequence{nodes: []node{
+		&anchor{wordBoundary},
+		&literal{s},
+		&anchor{wordBoundary},
+	}}}
 }
 
 // Find seeks string. The string MUST be there (unlike Maybe() method)
 func (v *VerbalExpression) Find(s string) *VerbalExpression {
-	return v.add(`(?:` + quote(s) + `)`)
+	return v.add(&literal{s})
 }
 
 // Not invert Find, meaning search something excepting "value". This
@@ -175,18 +403,24 @@ func (v *VerbalExpression) Not(value string) *VerbalExpression {
 	//return v.add(`(?!(` + quote(value) + `))`)
 	// because Golang doesn't implement ?!
 	// we create a pseudo negative system...
+	return v.add(notNode(value))
+}
 
-	runes := []rune(quote(value))
-	parts := make([]string, 0)
+// notNode returns the tree matched by Not(value)
+func notNode(value string) node {
+	parts := &alternation{}
 	prev := ""
-	for _, r := range runes {
-		parts = append(parts, prev+"[^"+string(r)+"]")
+	for _, r := range value {
+		part := &sequence{}
+		if prev != "" {
+			part.add(&literal{prev})
+		}
+		part.add(&class{negate: true, items: chars(string(r))})
+		parts.alts = append(parts.alts, part)
 		prev += string(r)
 	}
 
-	exp := strings.Join(parts, "|")
-	exp = "(?:" + exp + ")*?"
-	return v.add(exp)
+	return &quantifier{child: parts, min: 0, max: -1, lazy: true}
 }
 
 // Alias to Find()
@@ -196,7 +430,10 @@ func (v *VerbalExpression) Then(s string) *VerbalExpression {
 
 // Any accepts caracters to be matched
 func (v *VerbalExpression) Any(s string) *VerbalExpression {
-	return v.add(`(?:[` + quote(s) + `])`)
+	if s == "" {
+		return v.fail("Any", ErrEmptyClass, s)
+	}
+	return v.add(&class{items: chars(s)})
 }
 
 //AnyOf is an alias to Any
@@ -206,7 +443,12 @@ func (v *VerbalExpression) AnyOf(s string) *VerbalExpression {
 
 // LineBreak to find "\n" or "\r\n"
 func (v *VerbalExpression) LineBreak() *VerbalExpression {
-	return v.add(`(?:(?:\n)|(?:\r\n))`)
+	return v.add(lineBreakNode())
+}
+
+// lineBreakNode returns the tree matched by LineBreak()
+func lineBreakNode() node {
+	return &alternation{alts: []node{&literal{"\n"}, &literal{"\r\n"}}}
 }
 
 // Alias to LineBreak
@@ -218,31 +460,42 @@ func (v *VerbalExpression) Br() *VerbalExpression {
 // Think like this: Range(from, to [, from, to ...])
 func (v *VerbalExpression) Range(args ...interface{}) *VerbalExpression {
 	if len(args)%2 != 0 {
-		log.Panicf("Range: not even args number")
-	}
-
-	parts := make([]string, 3)
-	app := ""
-	for i := 0; i < len(args); i++ {
-		app += tostring(args[i])
-		if i%2 != 0 {
-			parts = append(parts, quote(app))
-			app = ""
-		} else {
-			app += "-"
+		return v.fail("Range", ErrOddRangeArgs, args...)
+	}
+	if len(args) == 0 {
+		return v.fail("Range", ErrEmptyClass)
+	}
+
+	bounds := make([]rune, len(args))
+	for i, arg := range args {
+		s, err := tostring(arg)
+		if err != nil {
+			return v.fail("Range", err, args...)
+		}
+		if utf8.RuneCountInString(s) != 1 {
+			return v.fail("Range", ErrRangeBound, args...)
+		}
+		bounds[i], _ = utf8.DecodeRuneInString(s)
+	}
+
+	c := &class{}
+	for i := 0; i < len(bounds); i += 2 {
+		if bounds[i] > bounds[i+1] {
+			return v.fail("Range", ErrInvalidRange, args...)
 		}
+		c.items = append(c.items, classItem{lo: bounds[i], hi: bounds[i+1]})
 	}
-	return v.add("[" + strings.Join(parts, "") + "]")
+	return v.add(c)
 }
 
 // Tab fetch tabulation char (\t)
 func (v *VerbalExpression) Tab() *VerbalExpression {
-	return v.add(`\t+`)
+	return v.add(&quantifier{child: &literal{"\t"}, min: 1, max: -1})
 }
 
 // Word matches any word (containing alpha char)
 func (v *VerbalExpression) Word() *VerbalExpression {
-	return v.add(`\w+`)
+	return v.add(&quantifier{child: &class{items: wordItems}, min: 1, max: -1})
 }
 
 // Multiply string s expression
@@ -275,11 +528,14 @@ func (v *VerbalExpression) Word() *VerbalExpression {
 func (v *VerbalExpression) Multiple(s string, mults ...int) *VerbalExpression {
 
 	if len(mults) > 2 {
-		panic("Multiple: you can only give 1 or to multipliers, min and max as int")
+		args := []interface{}{s}
+		for _, m := range mults {
+			args = append(args, m)
+		}
+		return v.fail("Multiple", ErrTooManyMultiples, args...)
 	}
-	// fetch multiplier if any
-	var min, max int = -1, -1
-	mult := "+"
+	// fetch multiplier if any, default is at least one time
+	var min, max int = 1, -1
 
 	if len(mults) > 0 {
 		min = mults[0]
@@ -288,49 +544,44 @@ func (v *VerbalExpression) Multiple(s string, mults ...int) *VerbalExpression {
 		}
 	}
 
-	if min == 0 && max == 1 {
-		// 0 or 1 time
-		mult = "?"
-	}
-
-	if min == 0 && max == -1 {
-		// 0 or more
-		mult = "*"
-	}
-
-	if min == 1 && max == -1 {
-		// at least 1 time
-		mult = "+"
-	}
-
-	if min > 1 && max == -1 {
-		//at least min times
-		mult = fmt.Sprintf("{%d,}", min)
-	}
-
-	if max > 1 {
-		if min > 0 {
-			// min to max times
-			mult = fmt.Sprintf("{%d,%d}", min, max)
-		} else {
-			// max times
-			mult = fmt.Sprintf("{,%d}", max)
+	if !validRepeat(min, max) {
+		args := []interface{}{s}
+		for _, m := range mults {
+			args = append(args, m)
 		}
+		return v.fail("Multiple", ErrInvalidRepeat, args...)
 	}
 
-	return v.add("(?:" + quote(s) + ")" + mult)
+	return v.add(&quantifier{child: &literal{s}, min: min, max: max})
 }
 
-// Or, chains an alternative VerbalExpression
+// Or, chains an alternative VerbalExpression: the whole expression matches,
+// or ve matches. Flags ve changed from New() apply to ve only. To get an
+// alternative at the current position of the expression, use Either() or
+// OneOf().
 func (v *VerbalExpression) Or(ve *VerbalExpression) *VerbalExpression {
-	v.parts = append(v.parts, ve.Regex().String()+"|")
+	if _, err := ve.Compile(); err != nil {
+		return v.fail("Or", err, ve)
+	}
+	if _, dup := duplicateName(v, ve); dup {
+		return v.fail("Or", ErrDuplicateName, ve)
+	}
+	v = v.fork()
+	v.invalidate()
+	v.alternatives = append(v.alternatives, ve.embed())
 	return v
 }
 
 // Add another VerbalExpression to the current.
 // Usefull to concatenate several complex search patterns
 func (v *VerbalExpression) And(ve *VerbalExpression) *VerbalExpression {
-	return v.add("(?:" + ve.Regex().String() + ")")
+	if _, err := ve.Compile(); err != nil {
+		return v.fail("And", err, ve)
+	}
+	if _, dup := duplicateName(v, ve); dup {
+		return v.fail("And", ErrDuplicateName, ve)
+	}
+	return v.add(ve.embed())
 }
 
 // WithAnyCase asks verbalexpressions to match with or without case sensitivity
@@ -358,20 +609,49 @@ func (v *VerbalExpression) MatchAllWithDot(enable bool) *VerbalExpression {
 	return v.removemodifier(DOTALL)
 }
 
-// Regex returns the regular expression to use to test on string.
-func (v *VerbalExpression) Regex() *regexp.Regexp {
+// Ungreedy makes all repetitions of the expression match as few characters
+// as possible, as if Lazy() was called after each one. Steps made lazy with
+// Lazy(), AnythingLazy() or SomethingLazy() stay lazy.
+func (v *VerbalExpression) Ungreedy(enable bool) *VerbalExpression {
+	if enable {
+		return v.addmodifier(UNGREEDY)
+	}
+	return v.removemodifier(UNGREEDY)
+}
 
-	if !v.compiled {
-		v.regexp = regexp.MustCompile(
-			strings.Join([]string{
-				strings.Join(v.parts, ""),
-				`(?` + v.getFlags() + `)`,
-				v.prefixes,
-				v.expression,
-				v.suffixes}, ""))
-		v.compiled = true
-	}
-	return v.regexp
+// Compile returns the regular expression to use to test on string. It returns
+// the first builder error (see Err()) or the regexp compilation error if the
+// expression is not valid.
+//
+// The expression is compiled once, until a builder method modifies it. Compile
+// and Regex may be called from several goroutines, as long as the expression
+// is not modified at the same time.
+func (v *VerbalExpression) Compile() (*regexp.Regexp, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.regexp == nil {
+		r, err := regexp.Compile(v.String())
+		if err != nil {
+			return nil, err
+		}
+		v.regexp = r
+	}
+	return v.regexp, nil
+}
+
+// Regex returns the regular expression to use to test on string. Like
+// regexp.MustCompile, it panics if the expression is not valid. Use Compile()
+// to get an error instead.
+func (v *VerbalExpression) Regex() *regexp.Regexp {
+	r, err := v.Compile()
+	if err != nil {
+		panic(err)
+	}
+	return r
 }
 
 func (v *VerbalExpression) StopAtFirst(enable bool) *VerbalExpression {
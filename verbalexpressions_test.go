@@ -5,6 +5,8 @@ package verbalexpressions
 
 import "testing"
 import "strings"
+import "errors"
+import "sync"
 
 func assertStringEquals(s1, s2 string, t *testing.T) {
 	if s1 != s2 {
@@ -48,7 +50,38 @@ func TestRange(t *testing.T) {
 
 }
 
-func TestPanicOnRangeOddParams(t *testing.T) {
+func TestErrorOnRangeOddParams(t *testing.T) {
+
+	v := New().Range("a", "z", 0, 9, 10)
+	if !errors.Is(v.Err(), ErrOddRangeArgs) {
+		t.Errorf("%v is not ErrOddRangeArgs", v.Err())
+	}
+	if _, err := v.Compile(); err != v.Err() {
+		t.Errorf("Compile() returned %v instead of %v", err, v.Err())
+	}
+	assertStringEquals(v.Err().Error(), `verbalexpressions: Range("a", "z", 0, 9, 10): not even args number`, t)
+}
+
+func TestErrorOnEmptySet(t *testing.T) {
+
+	for _, test := range []struct {
+		v      *VerbalExpression
+		expect string
+	}{
+		{New().Find("a").Any(""), `verbalexpressions: Any(""): empty character class`},
+		{New().AnythingBut(""), `verbalexpressions: AnythingBut(""): empty character class`},
+		{New().SomethingBut(""), `verbalexpressions: SomethingBut(""): empty character class`},
+		{New().Range(), `verbalexpressions: Range(): empty character class`},
+	} {
+		if !errors.Is(test.v.Err(), ErrEmptyClass) {
+			t.Errorf("%v is not ErrEmptyClass", test.v.Err())
+			continue
+		}
+		assertStringEquals(test.v.Err().Error(), test.expect, t)
+	}
+}
+
+func TestPanicOnInvalidRegex(t *testing.T) {
 
 	defer func() {
 		// if no panic... the test fails
@@ -57,7 +90,44 @@ func TestPanicOnRangeOddParams(t *testing.T) {
 		}
 	}()
 
-	New().Range("a", "z", 0, 9, 10)
+	New().Range("a", "z", 0, 9, 10).Regex()
+}
+
+func TestFirstErrorIsKept(t *testing.T) {
+
+	v := New().Find("foo").Range("a").Multiple("foo", 1, 2, 3).Range(make(chan int), 1)
+	err, ok := v.Err().(*StepError)
+	if !ok {
+		t.Fatalf("%v is not a *StepError", v.Err())
+	}
+	if err.Step != "Range" || len(err.Args) != 1 {
+		t.Errorf("%v is not the first error", err)
+	}
+
+	v = New().Range(make(chan int), 1)
+	if !errors.Is(v.Err(), ErrUnsupportedType) {
+		t.Errorf("%v is not ErrUnsupportedType", v.Err())
+	}
+}
+
+func TestCompileErrorFromSubExpression(t *testing.T) {
+
+	invalid := New().Multiple("foo", 1, 2, 3)
+
+	if err := New().Find("foo").Or(invalid).Err(); !errors.Is(err, ErrTooManyMultiples) {
+		t.Errorf("%v is not ErrTooManyMultiples", err)
+	}
+	if err := New().Find("foo").And(invalid).Err(); !errors.Is(err, ErrTooManyMultiples) {
+		t.Errorf("%v is not ErrTooManyMultiples", err)
+	}
+
+	r, err := New().Find("foo").Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.MatchString("a foo") {
+		t.Errorf("%v should match \"a foo\"", r)
+	}
 }
 
 func TestOneLine(t *testing.T) {
@@ -295,27 +365,81 @@ func TestMultipleMethod(t *testing.T) {
 	v = New().Multiple("foo", 0, 1)
 	assertStringEquals(v.Regex().String(), "(?m)(?:foo)?", t)
 
+	// "{,10}" is not a repetition for RE2, min must be set
 	v = New().Multiple("foo", 0, 10)
-	assertStringEquals(v.Regex().String(), "(?m)(?:foo){,10}", t)
+	assertStringEquals(v.Regex().String(), "(?m)(?:foo){0,10}", t)
 
 	v = New().Multiple("foo", 10)
 	assertStringEquals(v.Regex().String(), "(?m)(?:foo){10,}", t)
 
 	v = New().Multiple("foo", 10, 10)
-	assertStringEquals(v.Regex().String(), "(?m)(?:foo){10,10}", t)
+	assertStringEquals(v.Regex().String(), "(?m)(?:foo){10}", t)
 
 	v = New().Multiple("foo", 1, 10)
 	assertStringEquals(v.Regex().String(), "(?m)(?:foo){1,10}", t)
 
 }
 
-func TestPanicMultipleMethod(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("We should have panic here !")
+func TestErrorMultipleMethod(t *testing.T) {
+	v := New().Multiple("foo", 1, 10, 15)
+	if !errors.Is(v.Err(), ErrTooManyMultiples) {
+		t.Errorf("%v is not ErrTooManyMultiples", v.Err())
+	}
+
+	v = New().Multiple("foo", 10, 1)
+	if !errors.Is(v.Err(), ErrInvalidRepeat) {
+		t.Errorf("%v is not ErrInvalidRepeat", v.Err())
+	}
+}
+
+func TestRendering(t *testing.T) {
+
+	tests := []struct {
+		v      *VerbalExpression
+		expect string
+	}{
+		{New().Find("a.b").Maybe("s").Maybe("ss"), `(?m)a\.bs?(?:ss)?`},
+		{New().StartOfLine().Anything().Something().EndOfLine(), `(?m)^.*.+$`},
+		{New().AnythingBut("a-z").SomethingBut("]^"), `(?m)[^a\-z]*[^\]\^]+`},
+		{New().Find("a").LineBreak().Tab(), `(?m)a(?:\n|\r\n)\t+`},
+		{New().LineBreak(), `(?m)\n|\r\n`},
+		{New().Word().Any("ab"), `(?m)\w+[ab]`},
+		{New().Not("a.b"), `(?m)(?:[^a]|a[^.]|a\.[^b])*?`},
+		{New().SearchOneLine(true).Find("a"), `a`},
+		{New().Find("a").BeginCapture().Find("b").BeginCapture().Find("c"), `(?m)a(b(c))`},
+		{New().Find("a").And(New().Find("b").Or(New().Find("c"))), `(?m)a(?:b|c)`},
+	}
+	for _, test := range tests {
+		assertStringEquals(test.v.String(), test.expect, t)
+		if _, err := test.v.Compile(); err != nil {
+			t.Errorf("%s doesn't compile: %v", test.v, err)
 		}
-	}()
-	_ = New().Multiple("foo", 1, 10, 15)
+	}
+}
+
+func TestEndCaptureWithoutBegin(t *testing.T) {
+
+	v := New().BeginCapture().Find("a").EndCapture().EndCapture()
+	if !errors.Is(v.Err(), ErrNoOpenGroup) {
+		t.Errorf("%v is not ErrNoOpenGroup", v.Err())
+	}
+}
+
+func TestRangeBounds(t *testing.T) {
+
+	v := New().Range("0", "10")
+	if !errors.Is(v.Err(), ErrRangeBound) {
+		t.Errorf("%v is not ErrRangeBound", v.Err())
+	}
+}
+
+func TestOrSnapshot(t *testing.T) {
+
+	alt := New().Find("bar")
+	v := New().Find("foo").Or(alt)
+	alt.Find("baz")
+
+	assertStringEquals(v.String(), "(?m)foo|bar", t)
 }
 
 func TestSomethingMethods(t *testing.T) {
@@ -363,7 +487,7 @@ func TestWithAnyCase(t *testing.T) {
 	v := New().Find("message").WithAnyCase(true)
 	res := v.Test(s)
 	if !res {
-		t.Errorf("Error, message should match MESSAGE", v.Regex())
+		t.Errorf("Error, %v should match MESSAGE", v.Regex())
 	}
 }
 
@@ -489,33 +613,29 @@ func TestTABMethod(t *testing.T) {
 
 func TestToString(t *testing.T) {
 
-	res := tostring(int64(15))
+	res, _ := tostring(int64(15))
 	if res != "15" {
 		t.Errorf("%v is not string \"15\"", res)
 	}
 
-	res = tostring(uint64(15))
+	res, _ = tostring(uint64(15))
 	if res != "15" {
 		t.Errorf("%v is not string \"15\"", res)
 	}
 
-	res = tostring(uint(15))
+	res, _ = tostring(uint(15))
 	if res != "15" {
 		t.Errorf("%v is not string \"15\"", res)
 	}
 
 }
 
-func TestToStringMustPanic(t *testing.T) {
-
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("ToString must panic with unsupported types")
-		}
-	}()
+func TestToStringUnsupportedType(t *testing.T) {
 
 	s := make(chan int)
-	_ = tostring(s)
+	if _, err := tostring(s); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("ToString must fail with unsupported types, got %v", err)
+	}
 
 }
 
@@ -595,3 +715,226 @@ C`
 	}
 
 }
+
+func TestConcurrentUse(t *testing.T) {
+
+	v := New().Find("foo").BeginCapture().Word().EndCapture()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !v.Test("foobar") {
+				t.Errorf("%v should match foobar", v.Regex())
+			}
+			if c := v.Captures("foobar"); len(c) != 1 || c[0][1] != "bar" {
+				t.Errorf("%v is not [[foobar bar]]", c)
+			}
+			if r := v.Replace("foobar", "baz"); r != "baz" {
+				t.Errorf("%s is not baz", r)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRecompileAfterChange(t *testing.T) {
+
+	v := New().Find("foo")
+	if v.Test("bar") {
+		t.Errorf("%v should not match bar", v.Regex())
+	}
+	v.Or(New().Find("bar"))
+	if !v.Test("bar") {
+		t.Errorf("%v should match bar", v.Regex())
+	}
+}
+
+func TestClone(t *testing.T) {
+
+	v := New().Find("foo").BeginCapture().Find("bar")
+	c := v.Clone()
+	c.Find("baz").EndCapture().WithAnyCase(true)
+	v.EndCapture().Find("qux")
+
+	assertStringEquals(v.String(), "(?m)foo(bar)qux", t)
+	assertStringEquals(c.String(), "(?mi)foo(barbaz)", t)
+}
+
+func TestImmutable(t *testing.T) {
+
+	url := New().Immutable().StartOfLine().Find("http")
+	secure := url.Find("s://")
+	any := url.Maybe("s").Find("://")
+
+	assertStringEquals(url.String(), "(?m)^http", t)
+	assertStringEquals(secure.String(), "(?m)^https://", t)
+	assertStringEquals(any.String(), "(?m)^https?://", t)
+
+	// errors are not shared either
+	bad := url.Range("a")
+	if url.Err() != nil || bad.Err() == nil {
+		t.Errorf("error should only be set on the new expression: %v, %v", url.Err(), bad.Err())
+	}
+
+	// a mutable copy modifies itself
+	m := url.Mutable()
+	m.Find("s")
+	assertStringEquals(m.String(), "(?m)^https", t)
+	assertStringEquals(url.String(), "(?m)^http", t)
+}
+
+func TestImmutableGroups(t *testing.T) {
+
+	base := New().Immutable().BeginCapture().Find("a")
+	closed := base.EndCapture().Find("b")
+	inside := base.Find("b")
+
+	assertStringEquals(closed.String(), "(?m)(a)b", t)
+	assertStringEquals(inside.String(), "(?m)(ab)", t)
+	assertStringEquals(base.Or(New().Find("c")).String(), "(?m)(a)|c", t)
+	assertStringEquals(base.String(), "(?m)(a)", t)
+}
+
+func TestNamedCaptures(t *testing.T) {
+
+	s := "http://www.google.com https://github.com"
+	v := New().
+		BeginNamedCapture("scheme").Find("http").Maybe("s").EndCapture().
+		Find("://").
+		BeginNamedCapture("host").SomethingBut(" ").EndCapture()
+
+	assertStringEquals(v.String(), `(?m)(?P<scheme>https?)://(?P<host>[^ ]+)`, t)
+
+	res := v.NamedCaptures(s)
+	if len(res) != 2 {
+		t.Fatalf("%v is not length 2", res)
+	}
+	assertStringEquals(res[0]["scheme"], "http", t)
+	assertStringEquals(res[0]["host"], "www.google.com", t)
+	assertStringEquals(res[1]["scheme"], "https", t)
+	assertStringEquals(res[1]["host"], "github.com", t)
+
+	m := v.CaptureMap(s)
+	if len(m) != 2 || m["host"] != "www.google.com" {
+		t.Errorf("%v is not the first match", m)
+	}
+	if m := v.CaptureMap("ftp://foo"); m != nil {
+		t.Errorf("%v should be nil", m)
+	}
+}
+
+func TestNamedCapturesErrors(t *testing.T) {
+
+	for _, name := range []string{"", "with space", "(?P<x>"} {
+		if err := New().BeginNamedCapture(name).Err(); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("%q: %v is not ErrInvalidName", name, err)
+		}
+	}
+
+	v := New().BeginNamedCapture("a").EndCapture().BeginNamedCapture("a")
+	if !errors.Is(v.Err(), ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName", v.Err())
+	}
+
+	v = New().BeginNamedCapture("a").Or(New().BeginNamedCapture("b")).BeginNamedCapture("b")
+	if !errors.Is(v.Err(), ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName", v.Err())
+	}
+
+	x := New().BeginNamedCapture("x").Word().EndCapture()
+	v = New().BeginNamedCapture("x").Digit().EndCapture().And(x)
+	if err, ok := v.Err().(*StepError); !ok || err.Step != "And" || !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName of And", v.Err())
+	}
+	v = New().BeginNamedCapture("x").Digit().EndCapture().Or(x)
+	if err, ok := v.Err().(*StepError); !ok || err.Step != "Or" || !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName of Or", v.Err())
+	}
+	// in a group, not closed yet
+	v = New().BeginNamedCapture("x").Digit().And(x)
+	if !errors.Is(v.Err(), ErrDuplicateName) {
+		t.Errorf("%v is not ErrDuplicateName", v.Err())
+	}
+	if err := New().BeginNamedCapture("y").EndCapture().And(x).Or(New().Find("a")).Err(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestAnchors(t *testing.T) {
+
+	v := New().StartOfInput().Find("a").EndOfInput()
+	assertStringEquals(v.String(), `(?m)\Aa\z`, t)
+	if !v.Test("a") || v.Test("a\na") {
+		t.Errorf("%v should match a and not a\\na", v.Regex())
+	}
+
+	v = New().WordBoundary().Find("go").NotWordBoundary()
+	assertStringEquals(v.String(), `(?m)\bgo\B`, t)
+	if !v.Test("gopher") || v.Test("go on") || v.Test("ago") {
+		t.Errorf("%v should only match gopher", v.Regex())
+	}
+}
+
+func TestWholeWord(t *testing.T) {
+
+	v := New().WholeWord("cat")
+	assertStringEquals(v.String(), `(?m)\bcat\b`, t)
+	if v.Test("concatenate") || !v.Test("a cat, a dog") {
+		t.Errorf("%v should match cat and not concatenate", v.Regex())
+	}
+
+	v = New().WholeWord("a.b").Optional()
+	assertStringEquals(v.String(), `(?m)(?:\ba\.b\b)?`, t)
+}
+
+func TestLazySteps(t *testing.T) {
+
+	s := "<a><b>"
+
+	v := New().Find("<").AnythingLazy().Find(">")
+	assertStringEquals(v.String(), `(?m)<.*?>`, t)
+	res := v.Regex().FindAllString(s, -1)
+	if len(res) != 2 || res[0] != "<a>" || res[1] != "<b>" {
+		t.Errorf("%v is not [<a> <b>]", res)
+	}
+
+	v = New().Find("<").SomethingLazy().Find(">")
+	assertStringEquals(v.String(), `(?m)<.+?>`, t)
+	assertStringEquals(v.Regex().FindString("<><a>"), "<><a>", t)
+}
+
+func TestUngreedy(t *testing.T) {
+
+	s := "<a><b>"
+
+	v := New().Find("<").Anything().Find(">").Ungreedy(true)
+	assertStringEquals(v.String(), `(?mU)<.*>`, t)
+	assertStringEquals(v.Regex().FindString(s), "<a>", t)
+
+	// lazy steps stay lazy in ungreedy mode
+	v = New().Find("<").AnythingLazy().Find(">").Ungreedy(true)
+	assertStringEquals(v.String(), `(?mU)<.*>`, t)
+	assertStringEquals(v.Regex().FindString(s), "<a>", t)
+
+	v.Ungreedy(false)
+	assertStringEquals(v.getFlags(), "m", t)
+	assertStringEquals(v.Regex().FindString(s), "<a>", t)
+}
+
+func TestZeroValue(t *testing.T) {
+
+	var zero VerbalExpression
+	assertStringEquals(zero.String(), ``, t)
+	assertStringEquals(zero.Explain(), ``, t)
+	assertStringEquals(zero.DSL(), "search one line\nstop at first\n", t)
+	if !zero.Test("a") || zero.Err() != nil {
+		t.Errorf("zero value should match anything, error %v", zero.Err())
+	}
+
+	v := (&VerbalExpression{}).Immutable().Find("a")
+	assertStringEquals(v.String(), `a`, t)
+	v = (&VerbalExpression{}).Find("a").Or(New().Find("b"))
+	assertStringEquals(v.String(), `a|b`, t)
+}